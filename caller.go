@@ -5,8 +5,15 @@
 
 package grolog
 
+import (
+	"fmt"
+)
+
 // 调用器
+//
+// Deprecated: 使用 Logger.WithCallerSkip 代替.
 type Caller struct {
+	logger  *Logger    // 日志器
 	handler groHandler // 日志处理器
 	layer   int        // 调用层级
 }
@@ -18,73 +25,103 @@ func (c groCaller) IsValid() bool {
 }
 
 func (c groCaller) VerBose(a ...any) {
-	c.handler.Log(LevelVerBose, c.layer, a...)
+	c.handler.Log(c.logger, LevelVerBose, c.layer, a...)
 }
 
 func (c groCaller) Debug(a ...any) {
-	c.handler.Log(LevelDebug, c.layer, a...)
+	c.handler.Log(c.logger, LevelDebug, c.layer, a...)
 }
 
 func (c groCaller) Trace(a ...any) {
-	c.handler.Log(LevelTrace, c.layer, a...)
+	c.handler.Log(c.logger, LevelTrace, c.layer, a...)
 }
 
 func (c groCaller) Warning(a ...any) {
-	c.handler.Log(LevelWarning, c.layer, a...)
+	c.handler.Log(c.logger, LevelWarning, c.layer, a...)
 }
 
 func (c groCaller) Error(a ...any) {
-	c.handler.Log(LevelError, c.layer, a...)
+	c.handler.Log(c.logger, LevelError, c.layer, a...)
 }
 
 func (c groCaller) Fatal(a ...any) {
-	c.handler.Log(LevelFatal, c.layer, a...)
+	a = resolveArgs(a)
+	c.handler.Log(c.logger, LevelFatal, c.layer, a...)
+	msg := fmt.Sprint(a...)
+	c.logger.fatal(msg)
+}
+
+func (c groCaller) Panic(a ...any) {
+	a = resolveArgs(a)
+	c.handler.Log(c.logger, LevelPanic, c.layer, a...)
+	msg := fmt.Sprint(a...)
+	c.logger.panic(msg)
 }
 
 func (c groCaller) VerBoseln(a ...any) {
-	c.handler.Logln(LevelVerBose, c.layer, a...)
+	c.handler.Logln(c.logger, LevelVerBose, c.layer, a...)
 }
 
 func (c groCaller) Debugln(a ...any) {
-	c.handler.Logln(LevelDebug, c.layer, a...)
+	c.handler.Logln(c.logger, LevelDebug, c.layer, a...)
 }
 
 func (c groCaller) Traceln(a ...any) {
-	c.handler.Logln(LevelTrace, c.layer, a...)
+	c.handler.Logln(c.logger, LevelTrace, c.layer, a...)
 }
 
 func (c groCaller) Warningln(a ...any) {
-	c.handler.Logln(LevelWarning, c.layer, a...)
+	c.handler.Logln(c.logger, LevelWarning, c.layer, a...)
 }
 
 func (c groCaller) Errorln(a ...any) {
-	c.handler.Logln(LevelError, c.layer, a...)
+	c.handler.Logln(c.logger, LevelError, c.layer, a...)
 }
 
 func (c groCaller) Fatalln(a ...any) {
-	c.handler.Logln(LevelFatal, c.layer, a...)
+	a = resolveArgs(a)
+	c.handler.Logln(c.logger, LevelFatal, c.layer, a...)
+	msg := fmt.Sprintln(a...)
+	c.logger.fatal(msg)
+}
+
+func (c groCaller) Panicln(a ...any) {
+	a = resolveArgs(a)
+	c.handler.Logln(c.logger, LevelPanic, c.layer, a...)
+	msg := fmt.Sprintln(a...)
+	c.logger.panic(msg)
 }
 
 func (c groCaller) VerBosef(format string, args ...any) {
-	c.handler.Logf(LevelVerBose, c.layer, format, args...)
+	c.handler.Logf(c.logger, LevelVerBose, c.layer, format, args...)
 }
 
 func (c groCaller) Debugf(format string, args ...any) {
-	c.handler.Logf(LevelDebug, c.layer, format, args...)
+	c.handler.Logf(c.logger, LevelDebug, c.layer, format, args...)
 }
 
 func (c groCaller) Tracef(format string, args ...any) {
-	c.handler.Logf(LevelTrace, c.layer, format, args...)
+	c.handler.Logf(c.logger, LevelTrace, c.layer, format, args...)
 }
 
 func (c groCaller) Warningf(format string, args ...any) {
-	c.handler.Logf(LevelWarning, c.layer, format, args...)
+	c.handler.Logf(c.logger, LevelWarning, c.layer, format, args...)
 }
 
 func (c groCaller) Errorf(format string, args ...any) {
-	c.handler.Logf(LevelError, c.layer, format, args...)
+	c.handler.Logf(c.logger, LevelError, c.layer, format, args...)
 }
 
 func (c groCaller) Fatalf(format string, args ...any) {
-	c.handler.Logf(LevelFatal, c.layer, format, args...)
+	args = resolveArgs(args)
+	c.handler.Logf(c.logger, LevelFatal, c.layer, format, args...)
+	msg := fmt.Sprintf(format, args...)
+	c.logger.fatal(msg)
+}
+
+func (c groCaller) Panicf(format string, args ...any) {
+	args = resolveArgs(args)
+	c.handler.Logf(c.logger, LevelPanic, c.layer, format, args...)
+	msg := fmt.Sprintf(format, args...)
+	c.logger.panic(msg)
 }
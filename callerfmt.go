@@ -0,0 +1,115 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"bytes"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	CallerShort   int = iota // 文件名 (不含扩展名) 和行号, 如 server:42 (默认)
+	CallerFile               // 文件名和行号, 如 server.go:42
+	CallerPackage            // 包导入路径和文件名, 如 example.com/app/api/server.go:42 (main 包为 main/server.go:42)
+	CallerFull               // 完整路径 (去除调用位置前缀) 和行号
+)
+
+// 调用位置格式
+type groCallerFormat struct {
+	format   int    // 调用位置格式
+	trim     string // 完整路径去除的前缀 (目录或模块路径)
+	function bool   // 是否包含函数名称
+}
+
+// 写入调用位置 (格式: [位置 函数名称], 获取失败时为 [???#?])
+func (f *groCallerFormat) write(buf *bytes.Buffer, pc uintptr, file string, line int) {
+	buf.WriteString("[")
+	if pc == 0 {
+		buf.WriteString("???#?")
+		buf.WriteString("]")
+		return
+	}
+
+	// 函数名称 (包导入路径由函数名称获取)
+	name := ""
+	if f.function || f.format == CallerPackage || (f.format == CallerFull && f.trim != "") {
+		if fn := runtime.FuncForPC(pc); fn != nil {
+			name = fn.Name()
+		}
+	}
+	switch f.format {
+	case CallerFile:
+		buf.WriteString(path.Base(file))
+	case CallerPackage:
+		if pkg := funcPackage(name); pkg != "" {
+			buf.WriteString(pkg + "/" + path.Base(file))
+		} else {
+			buf.WriteString(path.Join(path.Base(path.Dir(file)), path.Base(file)))
+		}
+	case CallerFull:
+		buf.WriteString(f.trimPath(file, funcPackage(name)))
+	default:
+		buf.WriteString(strings.TrimSuffix(path.Base(file), path.Ext(file)))
+	}
+	buf.WriteString(":")
+	buf.WriteString(strconv.Itoa(line))
+	if f.function && name != "" {
+		buf.WriteString(" ")
+		buf.WriteString(shortFuncName(name))
+	}
+	buf.WriteString("]")
+}
+
+// 去除完整路径的前缀 (前缀为文件路径的前缀时直接去除, 否则按包导入路径去除, 如模块路径, 不要求使用 -trimpath 编译)
+func (f *groCallerFormat) trimPath(file string, pkg string) string {
+	if f.trim == "" {
+		return file
+	}
+	if rest, ok := cutPathPrefix(file, f.trim); ok {
+		return rest
+	}
+	if pkg != "" {
+		if rest, ok := cutPathPrefix(pkg+"/", f.trim); ok {
+			return rest + path.Base(file)
+		}
+	}
+	return file
+}
+
+// 去除路径前缀 (前缀须在路径分隔处结束, 如 example.com/app 不匹配 example.com/application)
+func cutPathPrefix(s string, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+	rest := s[len(prefix):]
+	if strings.HasSuffix(prefix, "/") {
+		return rest, true
+	}
+	if strings.HasPrefix(rest, "/") {
+		return rest[1:], true
+	}
+	return s, false
+}
+
+// 获取函数所在包的导入路径 (如 example.com/app/api.(*Server).handle 返回 example.com/app/api)
+func funcPackage(name string) string {
+	i := strings.LastIndexByte(name, '/') + 1
+	if j := strings.IndexByte(name[i:], '.'); j >= 0 {
+		return name[:i+j]
+	}
+	return ""
+}
+
+// 去除函数名称的包路径 (保留包名)
+func shortFuncName(name string) string {
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
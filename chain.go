@@ -0,0 +1,343 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// 链式校验格式
+//
+// 每条日志消息结尾 (换行之前) 追加 " ~<校验值>", 校验值为 HMAC-SHA256(密钥, 上一校验值 + 日志消息),
+// 未设置密钥时为 SHA256(上一校验值 + 日志消息)。新建或切换的日志文件以链头开始, 链头记录上一个日志文件
+// 最后的校验值 (新的进程从目录中最新的日志文件末尾恢复), 目录中的第一个日志文件以全零的起始链头开始,
+// 因此日志消息被修改、删除或插入, 以及日志文件被删除, 都会导致校验失败。多行日志消息中除最后一行以外,
+// 行尾的 " ~<校验值>" 转义为 " \~<校验值>", 链头格式的行前加 "\", 避免校验时被误认为日志消息结尾或链头。
+const (
+	chainHeader = "# grolog-chain " // 链头前缀 (之后为上一校验值)
+	chainSuffix = " ~"              // 校验值前缀
+)
+
+const (
+	chainHexSize  = sha256.Size * 2                     // 校验值的十六进制长度
+	chainTailSize = len(chainHeader) + chainHexSize + 1 // 恢复校验值时读取的文件末尾长度
+)
+
+// 链式校验器
+type groChain struct {
+	mac    hash.Hash // 摘要算法
+	sum    []byte    // 当前校验值 (初始全零)
+	next   []byte    // 待提交的校验值
+	line   []byte    // 日志消息缓冲区
+	linked bool      // 是否已确定当前校验值 (恢复或写入链头后)
+}
+
+// 创建链式校验器
+func newChain(key []byte) *groChain {
+	c := &groChain{sum: make([]byte, sha256.Size)}
+	if len(key) > 0 {
+		c.mac = hmac.New(sha256.New, key)
+	} else {
+		c.mac = sha256.New()
+	}
+	return c
+}
+
+// 计算校验值
+func (c *groChain) digest(prev, text []byte) []byte {
+	c.mac.Reset()
+	c.mac.Write(prev)
+	c.mac.Write(text)
+	return c.mac.Sum(nil)
+}
+
+// 追加校验值 (返回的日志消息在下次调用前有效, 写入成功后调用 commit)
+func (c *groChain) link(b []byte) []byte {
+	c.line = appendChainText(c.line[:0], bytes.TrimSuffix(b, []byte{'\n'}))
+	c.next = c.digest(c.sum, c.line)
+
+	c.line = append(c.line, chainSuffix...)
+	c.line = appendChainSum(c.line, c.next)
+	c.line = append(c.line, '\n')
+	return c.line
+}
+
+// 追加日志消息 (转义除最后一行以外的行尾校验值格式和链头格式)
+func appendChainText(b, text []byte) []byte {
+	for {
+		i := bytes.IndexByte(text, '\n')
+		if i < 0 {
+			return append(b, text...)
+		}
+		line := text[:i]
+		if _, ok := parseChainHeader(line); ok {
+			b = append(b, '\\')
+		}
+		if _, _, ok := parseChainLine(line); ok {
+			n := len(line) - chainHexSize - len(chainSuffix)
+			b = append(b, line[:n+1]...)
+			b = append(b, '\\')
+			b = append(b, line[n+1:]...)
+		} else {
+			b = append(b, line...)
+		}
+		b = append(b, '\n')
+		text = text[i+1:]
+	}
+}
+
+// 提交校验值
+func (c *groChain) commit() {
+	c.sum = c.next
+}
+
+// 生成链头
+func (c *groChain) header() []byte {
+	c.linked = true
+	return append(appendChainSum([]byte(chainHeader), c.sum), '\n')
+}
+
+// 从已存在的日志文件末尾恢复校验值 (末尾不是完整的日志消息或链头时返回false)
+func (c *groChain) resume(tail []byte) bool {
+	if len(tail) == 0 {
+		return false
+	}
+	if i := bytes.LastIndexByte(tail[:len(tail)-1], '\n'); i >= 0 {
+		tail = tail[i+1:]
+	}
+	line := bytes.TrimSuffix(tail, []byte{'\n'})
+	if len(line) == len(tail) {
+		return false
+	}
+
+	sum, ok := parseChainHeader(line)
+	if !ok {
+		_, sum, ok = parseChainLine(line)
+	}
+	if !ok {
+		return false
+	}
+	c.sum, c.linked = sum, true
+	return true
+}
+
+// 追加十六进制校验值
+func appendChainSum(b, sum []byte) []byte {
+	n := len(b)
+	b = append(b, make([]byte, hex.EncodedLen(len(sum)))...)
+	hex.Encode(b[n:], sum)
+	return b
+}
+
+// 解析链头
+func parseChainHeader(line []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(line, []byte(chainHeader)) {
+		return nil, false
+	}
+	return parseChainSum(line[len(chainHeader):])
+}
+
+// 解析日志消息的校验值 (返回日志消息和校验值)
+func parseChainLine(line []byte) ([]byte, []byte, bool) {
+	i := len(line) - chainHexSize - len(chainSuffix)
+	if i < 0 || string(line[i:i+len(chainSuffix)]) != chainSuffix {
+		return nil, nil, false
+	}
+	sum, ok := parseChainSum(line[i+len(chainSuffix):])
+	return line[:i], sum, ok
+}
+
+// 解析校验值
+func parseChainSum(b []byte) ([]byte, bool) {
+	if len(b) != chainHexSize {
+		return nil, false
+	}
+	sum := make([]byte, sha256.Size)
+	if _, err := hex.Decode(sum, b); err != nil {
+		return nil, false
+	}
+	return sum, true
+}
+
+// 链式校验错误
+type ChainError struct {
+	File   string // 日志文件
+	Line   int    // 首个断开的行号 (从1开始)
+	Reason string // 断开原因
+}
+
+// 错误信息
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Reason)
+}
+
+// 校验日志文件的链式校验值 (按写入顺序传入日志文件, 返回首个断开位置的 *ChainError)
+//
+// 第一个日志文件必须以全零的起始链头开始, 因此最早的日志文件被删除也会导致校验失败;
+// 最早的日志文件已按计划轮转或清理时, 使用 VerifyChainFrom 从已知的校验值开始校验.
+func VerifyChain(key []byte, files ...string) error {
+	return verifyChain(newChain(key), files)
+}
+
+// 从已知的校验值开始校验日志文件 (prev 为上次校验时最后一条日志消息的十六进制校验值, 即行尾 ~ 之后的值)
+//
+// 第一个日志文件的链头必须等于 prev, 否则返回 *ChainError.
+func VerifyChainFrom(key []byte, prev string, files ...string) error {
+	sum, ok := parseChainSum([]byte(prev))
+	if !ok {
+		return fmt.Errorf("invalid chain value %q", prev)
+	}
+	c := newChain(key)
+	c.sum = sum
+	return verifyChain(c, files)
+}
+
+// 按顺序校验日志文件 (第一个链头必须等于校验器的当前校验值)
+func verifyChain(c *groChain, files []string) error {
+	started := false
+	for _, name := range files {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = c.verify(file, name, &started)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 校验单个日志文件
+func (c *groChain) verify(r io.Reader, name string, started *bool) error {
+	var text []byte // 多行日志消息
+	first := 0      // 日志消息的起始行号
+
+	reader := bufio.NewReader(r)
+	for num := 1; ; num++ {
+		line, err := reader.ReadBytes('\n')
+		if len(line) == 0 && err == io.EOF {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if line[len(line)-1] != '\n' {
+			return &ChainError{File: name, Line: num, Reason: "incomplete record"}
+		}
+		line = line[:len(line)-1]
+
+		if _, ok := parseChainHeader(line); ok && text != nil {
+			// 链头之前的日志消息不完整 (进程崩溃后重新打开日志文件)
+			return &ChainError{File: name, Line: first, Reason: "incomplete record"}
+		}
+		if text == nil {
+			first = num
+			if sum, ok := parseChainHeader(line); ok {
+				if !hmac.Equal(sum, c.sum) && *started {
+					return &ChainError{File: name, Line: num, Reason: "chain header does not match previous record"}
+				}
+				if !hmac.Equal(sum, c.sum) {
+					return &ChainError{File: name, Line: num, Reason: "chain header does not match chain start"}
+				}
+				c.sum, *started = sum, true
+				continue
+			}
+			if !*started {
+				return &ChainError{File: name, Line: num, Reason: "missing chain header"}
+			}
+		}
+
+		msg, sum, ok := parseChainLine(line)
+		if !ok {
+			// 多行日志消息的续行
+			text = append(append(text, line...), '\n')
+			continue
+		}
+		text = append(text, msg...)
+		if !hmac.Equal(c.digest(c.sum, text), sum) {
+			return &ChainError{File: name, Line: first, Reason: "chain value mismatch"}
+		}
+		c.sum, text = sum, nil
+	}
+	if text != nil {
+		return &ChainError{File: name, Line: first, Reason: "incomplete record"}
+	}
+	return nil
+}
+
+// 列出日志文件 (按写入顺序排序)
+//
+// 按修改时间排序, 修改时间相同时按文件名排序, 文件名中的序号按数值比较, 且没有序号的文件 (如 App.log)
+// 排在有序号的文件 (如 App(1).log) 之前. 按序号循环覆盖的日志文件以修改时间为准.
+// 只列出以 name 命名的日志文件, 不包含以其为前缀的其他日志器的文件 (如 Apple.log、App_audit.log).
+func ListLogFiles(dir string, name string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type logFile struct {
+		path string
+		info os.FileInfo
+	}
+	var files []logFile
+	re := logFileRe(name)
+	for _, entry := range entries {
+		if entry.IsDir() || !re.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, logFile{path: filepath.Join(dir, entry.Name()), info: info})
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		ti, tj := files[i].info.ModTime(), files[j].info.ModTime()
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		bi, ni := splitLogFileNum(files[i].info.Name())
+		bj, nj := splitLogFileNum(files[j].info.Name())
+		if bi != bj {
+			return bi < bj
+		}
+		return ni < nj
+	})
+
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+	}
+	return paths, nil
+}
+
+// 日志文件序号
+var logFileNumRe = regexp.MustCompile(`^(.*?)(?:\((\d+)\))?\.log$`)
+
+// 拆分日志文件名和序号 (没有序号时为0)
+func splitLogFileNum(name string) (string, int) {
+	m := logFileNumRe.FindStringSubmatch(name)
+	if m == nil {
+		return name, 0
+	}
+	num, _ := strconv.Atoi(m[2])
+	return m[1], num
+}
@@ -0,0 +1,60 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// 解密加密的日志文件并输出到标准输出
+//
+// 用法: grolog-decrypt [-key 十六进制密钥] 日志文件...
+//
+// 未指定密钥时读取环境变量 GROLOG_KEY。多个日志文件按参数顺序输出,
+// 最后不完整的数据块被忽略。
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tayne3/grolog"
+)
+
+func main() {
+	keyHex := flag.String("key", os.Getenv("GROLOG_KEY"), "hex encoded encrypt key (default $GROLOG_KEY)")
+	flag.Parse()
+
+	key, err := hex.DecodeString(*keyHex)
+	if err != nil || len(key) == 0 {
+		fmt.Fprintln(os.Stderr, "grolog-decrypt: invalid or missing key")
+		os.Exit(2)
+	}
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: grolog-decrypt [-key hex] file...")
+		os.Exit(2)
+	}
+
+	for _, name := range flag.Args() {
+		if err := decrypt(name, key); err != nil {
+			fmt.Fprintf(os.Stderr, "grolog-decrypt: %s: %v\n", name, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// 解密日志文件
+func decrypt(name string, key []byte) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r, err := grolog.NewDecryptReader(file, key)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(os.Stdout, r)
+	return err
+}
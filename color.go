@@ -0,0 +1,63 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"os"
+)
+
+const (
+	ColorAuto   int = iota // 自动检测 (默认, FORCE_COLOR 优先启用, NO_COLOR 禁用, 否则输出为终端时启用)
+	ColorAlways            // 始终启用颜色
+	ColorNever             // 始终禁用颜色
+)
+
+// 生成日志级别颜色 (禁用颜色时返回nil)
+func newColors(c *Config, out *os.File) []string {
+	if out == nil || !colorEnabled(c.Color, out) {
+		return nil
+	}
+
+	colors := append([]string(nil), levelStyleStarts...)
+	for level, sgr := range c.ColorPalette {
+		if level < LevelVerBose || level > LevelPanic {
+			continue
+		}
+		if sgr == "" {
+			colors[level] = ""
+		} else {
+			colors[level] = "\x1b[" + sgr + "m"
+		}
+	}
+	return colors
+}
+
+// 是否启用颜色
+func colorEnabled(mode int, out *os.File) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	if force, ok := os.LookupEnv("FORCE_COLOR"); ok && force != "0" && force != "false" {
+		return true
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(out)
+}
+
+// 是否为终端
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
@@ -6,9 +6,10 @@
 package grolog
 
 import (
+	"crypto/cipher"
+	"fmt"
 	"os"
 	"path/filepath"
-	"runtime/debug"
 	"strings"
 	"time"
 )
@@ -19,7 +20,8 @@ const (
 	LevelTrace              // 提示信息, 用于跟踪程序执行流程
 	LevelWarning            // 警告信息, 用于打印警告信息
 	LevelError              // 错误信息, 用于记录非致命性的错误信息
-	LevelFatal              // 致命错误, 用于记录致命的错误信息
+	LevelFatal              // 致命错误, 用于记录致命的错误信息 (记录后执行致命错误动作)
+	LevelPanic              // 异常错误, 用于记录异常的错误信息 (记录后抛出异常)
 )
 
 // 日志级别字符串
@@ -30,6 +32,7 @@ var levelStrings = []string{
 	LevelWarning: "WARNG",
 	LevelError:   "ERROR",
 	LevelFatal:   "FATAL",
+	LevelPanic:   "PANIC",
 }
 
 // 日志样式字符串-起始
@@ -40,6 +43,7 @@ var levelStyleStarts = []string{
 	LevelWarning: "\x1b[33;2m",
 	LevelError:   "\x1b[31;2m",
 	LevelFatal:   "\x1b[31;2m",
+	LevelPanic:   "\x1b[35;2m",
 }
 
 // 日志样式字符串-结束
@@ -53,6 +57,15 @@ const (
 	StyleDetail            // 调试日志 (会影响性能, 包含 级别、时间、文件名、行号、消息)
 )
 
+const (
+	FatalExit     int = iota // 关闭日志器并退出程序 (默认动作)
+	FatalPanic               // 抛出异常
+	FatalContinue            // 仅记录日志, 继续执行
+)
+
+// 以退出码0退出程序 (退出码为0时使用默认值, 需要以0退出时设置为该值)
+const ExitSuccess int = -1
+
 const (
 	_   = 1 << (10 * iota)
 	KiB // 1024
@@ -63,6 +76,8 @@ const (
 const (
 	defaultLevel          = LevelWarning // 默认日志级别
 	defaultStyle          = StyleBrief   // 默认日志样式
+	defaultFatalAction    = FatalExit    // 默认致命错误动作
+	defaultExitCode       = 1            // 默认退出码
 	defaultMaxAsynExec    = 100          // 默认异步执行数量上限
 	defaultMaxAsynBuffer  = 128          // 默认异步缓冲大小
 	defaultMaxWriteBuffer = 4096         // 默认日志文件缓冲大小
@@ -70,31 +85,84 @@ const (
 	defaultMaxFileSize    = 5 * MiB      // 默认日志文件大小上限
 	defaultFileDir        = "log"        // 默认日志文件保存目录
 	defaultFlashInterval  = "3h0m0s"     // 默认日志文件刷新间隔 (3h)
+	defaultFlashLatency   = "0s"         // 默认日志文件最大缓冲时长 (默认禁用)
 	defaultExpireTime     = "0s"         // 默认日志文件过期时间 (默认禁用)
+	defaultRetryInterval  = "1s"         // 默认日志文件重新打开间隔 (1s)
+	defaultFallbackMemory = 64 * KiB     // 默认内存备用缓冲大小
+	defaultRedactMask     = "[REDACTED]" // 默认脱敏替换文本
+	maxRetryInterval      = time.Minute  // 日志文件重新打开间隔上限
+)
+
+const (
+	defaultTimeFormat = "06.01.02-15:04:05.000" // 默认时间格式 (简要日志和详细日志)
+	defaultTimeZone   = "Local"                 // 默认时区
+	defaultStackLevel = LevelError              // 默认调用堆栈级别
 )
 
 // 定义配置选项
 type Config struct {
 	logger         *Logger            `json:"-"`              // 日志器 (永不为空)
+	exec           *groExecutor       `json:"-"`              // 日志器执行器 (永不为空)
+	errs           *groErrors         `json:"-"`              // 内部错误记录 (永不为空)
+	memory         *groMemory         `json:"-"`              // 内存备用缓冲区 (备用输出链包含内存时不为空)
+	redactor       *groRedactor       `json:"-"`              // 脱敏器 (未配置脱敏规则时为空)
+	aead           cipher.AEAD        `json:"-"`              // 日志文件加密算法 (未设置加密密钥时为空)
+	printTime      *groTimeFormat     `json:"-"`              // 日志打印时间格式 (永不为空)
+	saveTime       *groTimeFormat     `json:"-"`              // 日志文件时间格式 (与日志打印相同时为同一对象)
+	caller         *groCallerFormat   `json:"-"`              // 调用位置格式 (永不为空)
+	vmodule        *groVModule        `json:"-"`              // 调用位置级别 (未配置调用位置级别时为空)
 	startTime      time.Time          `json:"-"`              // 启始时间 (创建时自动填充)
-	FatalHandling  func(*Logger, any) `json:"-"`              // 异常日志处理函数 (为空时使用默认值)
-	MsgCallback    func(int, string)  `json:"-"`              // 日志消息回调函数 (默认为空)
-	GoExec         func(func())       `json:"-"`              // 异步执行函数 (为空时使用go语句执行)
+	FatalHandling  func(*Logger, any) `json:"-"`              // 异常日志处理函数 (致命日志或捕获的异常写入并刷新后, 在调用方协程中以致命日志的消息或捕获的异常值调用, 为空时执行致命错误动作)
+	ErrorHandler   func(error)        `json:"-"`              // 内部错误处理函数 (写入失败、钩子错误等, 为空时输出到标准错误; 调用时不持有日志文件锁, 异步模式下推送日志时的错误通过执行器报告, 可在其中记录日志; 可能在 New 返回前调用, 此时日志器尚未返回)
+	MsgCallback    func(int, string)  `json:"-"`              // 日志消息回调函数 (已弃用, 使用日志钩子代替, 默认为空)
+	hooks          []groHook          `json:"-"`              // 日志钩子 (默认为空)
+	GoExec         func(func())       `json:"-"`              // 异步执行函数 (未设置异步执行器时有效, 为空时使用go语句执行)
+	Executor       Executor           `json:"-"`              // 异步执行器 (为空时使用异步执行函数)
+	SpanExtractor  SpanExtractor      `json:"-"`              // 调用链提取器 (带上下文的日志方法通过它添加调用链字段, 默认为空)
 	Level          int                `json:"Level"`          // 日志级别 (默认警告级别, 值无效时使用默认值)
+	NamedLevels    map[string]int     `json:"NamedLevels"`    // 命名日志器级别 (日志器名称到级别, 命名日志器使用最近的已配置祖先的级别, 值无效的项被忽略, 默认为空)
+	VModule        string             `json:"VModule"`        // 调用位置级别 (如 "storage*=verbose,http/*=debug", 按调用位置所在文件或包覆盖日志级别, 匹配的规则优先于命名日志器级别, 无效的规则被忽略, 默认为空)
 	Style          int                `json:"Style"`          // 日志样式 (默认简要样式, 值无效时使用默认值)
+	FatalAction    int                `json:"FatalAction"`    // 致命错误动作 (默认退出程序, 值无效时使用默认值)
+	ExitCode       int                `json:"ExitCode"`       // 致命错误退出码 (致命错误动作为退出程序时有效, 为0或小于 ExitSuccess 时使用默认值, 为 ExitSuccess 时以0退出)
 	EnableAsyn     bool               `json:"EnableAsyn"`     // 是否启用异步模式 (默认禁用异步模式, 同步模式的性能可能会优于异步模式，但异步模式下资源使用更加可控)
 	EnableFileTime bool               `json:"EnableFileTime"` // 是否启用文件时间 (默认禁用文件名包含时间信息)
+	EnableChain    bool               `json:"EnableChain"`    // 是否启用链式校验 (默认禁用, 启用后日志文件的每条日志消息附加校验值, 可通过 VerifyChain 检查篡改)
 	DisableSave    bool               `json:"DisableSave"`    // 是否禁用日志文件 (默认启用日志文件)
 	DisablePrint   bool               `json:"DisablePrint"`   // 是否禁用日志打印 (默认启用日志打印)
-	MaxAsynExec    int                `json:"MaxAsynExec"`    // 异步执行数量上限 (启用异步模式时有效, 小于等于0时使用默认值)
-	MaxAsynBuffer  int                `json:"MaxAsynBuffer"`  // 异步消息缓冲大小 (启用异步模式时有效, 小于0时使用默认值)
+	TimeFormat     string             `json:"TimeFormat"`     // 时间格式 (Go 时间布局或 TimeUnix 等 Unix 时间戳精度, 为空时使用默认值)
+	SaveTimeFormat string             `json:"SaveTimeFormat"` // 日志文件时间格式 (为空时与时间格式相同)
+	TimeZone       string             `json:"TimeZone"`       // 时区 (Local、UTC、IANA 时区名称或固定偏移如 +08:00, 默认本地时区, 值无效时使用默认值)
+	CallerFormat   int                `json:"CallerFormat"`   // 调用位置格式 (详细日志有效, 默认文件名和行号, 值无效时使用默认值)
+	CallerTrim     string             `json:"CallerTrim"`     // 调用位置去除的路径前缀 (调用位置格式为完整路径时有效, 如模块根目录或模块路径, 模块路径按包导入路径去除, 不适用于 main 包)
+	EnableFunc     bool               `json:"EnableFunc"`     // 是否启用调用函数名称 (详细日志有效, 默认禁用)
+	EnableStack    bool               `json:"EnableStack"`    // 是否启用调用堆栈 (默认禁用, 启用后达到调用堆栈级别的日志附加调用堆栈)
+	StackLevel     int                `json:"StackLevel"`     // 调用堆栈级别 (启用调用堆栈时有效, 默认错误级别, 值无效时使用默认值)
+	SaveCaller     bool               `json:"SaveCaller"`     // 是否在日志文件中写入调用位置 (详细日志有效, 默认只在日志打印中写入)
+	PrintTarget    int                `json:"PrintTarget"`    // 日志打印目标 (默认标准输出, 值无效时使用默认值)
+	Color          int                `json:"Color"`          // 日志打印颜色模式 (默认自动检测, 值无效时使用默认值)
+	ColorPalette   map[int]string     `json:"ColorPalette"`   // 日志级别颜色 (日志级别到 SGR 参数, 如 "31;1", 为空字符串时不着色, 未设置的级别使用默认颜色)
+	PrintEscape    int                `json:"PrintEscape"`    // 日志打印转义方式 (默认原样输出, 值无效时使用默认值)
+	SaveEscape     int                `json:"SaveEscape"`     // 日志文件转义方式 (默认原样输出, 值无效时使用默认值)
+	MaxAsynExec    int                `json:"MaxAsynExec"`    // 异步执行数量上限 (已弃用, 异步模式固定使用单个消费者)
+	MaxAsynBuffer  int                `json:"MaxAsynBuffer"`  // 异步消息缓冲大小 (启用异步模式时有效, 向上取整为2的幂, 最小为2, 小于0时使用默认值)
 	MaxWriteBuffer int                `json:"MaxWriteBuffer"` // 日志文件缓冲大小 (启用日志文件时有效, 小于0时使用默认值)
 	MaxFileCount   int                `json:"MaxFileCount"`   // 日志文件数量上限 (启用日志文件时有效, 小于等于0时使用默认值)
 	MaxFileSize    int64              `json:"MaxFileSize"`    // 日志文件大小上限 (启用日志文件时有效, 小于等于0时使用默认值)
 	FileDir        string             `json:"FileDir"`        // 日志文件保存目录 (启用日志文件时有效, 为空时使用程序运行路径下的log目录)
 	FileName       string             `json:"FileName"`       // 日志文件保存名称 (启用日志文件时有效, 为空时使用程序名称)
 	FlashInterval  string             `json:"FlashInterval"`  // 日志文件刷新间隔 (启用日志文件时有效, 等于0时禁用, 值无效时使用默认值)
+	FlashLatency   string             `json:"FlashLatency"`   // 日志文件最大缓冲时长 (启用日志文件时有效, 等于0时禁用, 值无效时使用默认值)
 	ExpireTime     string             `json:"ExpireTime"`     // 日志文件过期时间 (启用日志文件时有效, 等于0时禁用, 值无效时使用默认值)
+	RetryInterval  string             `json:"RetryInterval"`  // 日志文件重新打开间隔 (日志文件不可用时有效, 每次失败后翻倍, 最大1分钟, 等于0时禁用, 值无效时使用默认值)
+	Fallback       []int              `json:"Fallback"`       // 备用输出链 (日志文件不可用时按顺序尝试, 默认为空即丢弃日志)
+	FallbackMemory int                `json:"FallbackMemory"` // 内存备用缓冲大小 (备用输出链包含内存时有效, 小于等于0时使用默认值)
+	Redact         int                `json:"Redact"`         // 内置脱敏规则 (RedactAPIKey 等规则的组合, 默认禁用)
+	RedactPatterns []string           `json:"RedactPatterns"` // 自定义脱敏正则表达式 (匹配内容整体替换, 默认为空)
+	RedactFields   []string           `json:"RedactFields"`   // 脱敏字段名称 (不区分大小写, 字段值整体替换, 默认为空)
+	RedactMask     string             `json:"RedactMask"`     // 脱敏替换文本 (为空时使用默认值)
+	ChainKey       []byte             `json:"-"`              // 链式校验密钥 (启用链式校验时有效, 为空时使用 SHA-256, 否则使用 HMAC-SHA256)
+	EncryptKey     []byte             `json:"-"`              // 日志文件加密密钥 (AES-GCM, 长度为16、24或32字节, 为空时不加密, 无效时报告错误并禁用日志文件)
 }
 
 // 配置选项
@@ -106,14 +174,36 @@ func DefaultConfig() *Config {
 		logger:         nil,
 		startTime:      time.Now(),
 		FatalHandling:  nil,
+		ErrorHandler:   nil,
 		MsgCallback:    nil,
 		GoExec:         nil,
+		Executor:       nil,
+		SpanExtractor:  nil,
 		Level:          defaultLevel,
+		NamedLevels:    nil,
+		VModule:        "",
 		Style:          defaultStyle,
+		FatalAction:    defaultFatalAction,
+		ExitCode:       defaultExitCode,
 		EnableAsyn:     false,
 		EnableFileTime: false,
+		EnableChain:    false,
 		DisableSave:    false,
 		DisablePrint:   false,
+		TimeFormat:     defaultTimeFormat,
+		SaveTimeFormat: "",
+		TimeZone:       defaultTimeZone,
+		CallerFormat:   CallerShort,
+		CallerTrim:     "",
+		EnableFunc:     false,
+		SaveCaller:     false,
+		EnableStack:    false,
+		StackLevel:     defaultStackLevel,
+		PrintTarget:    PrintStdout,
+		Color:          ColorAuto,
+		ColorPalette:   nil,
+		PrintEscape:    EscapeNone,
+		SaveEscape:     EscapeNone,
 		MaxAsynExec:    defaultMaxAsynExec,
 		MaxAsynBuffer:  defaultMaxAsynBuffer,
 		MaxWriteBuffer: defaultMaxWriteBuffer,
@@ -122,31 +212,110 @@ func DefaultConfig() *Config {
 		FileDir:        defaultFileDir,
 		FileName:       "",
 		FlashInterval:  defaultFlashInterval,
+		FlashLatency:   defaultFlashLatency,
 		ExpireTime:     defaultExpireTime,
+		RetryInterval:  defaultRetryInterval,
+		Fallback:       nil,
+		FallbackMemory: defaultFallbackMemory,
+		Redact:         0,
+		RedactPatterns: nil,
+		RedactFields:   nil,
+		RedactMask:     defaultRedactMask,
 	}
 }
 
+// 创建定时刷新器
+func (c *Config) newFlusher(flush func()) *groFlusher {
+	interval, _ := time.ParseDuration(c.FlashInterval)
+	latency, _ := time.ParseDuration(c.FlashLatency)
+	return newFlusher(c.exec, interval, latency, flush)
+}
+
 // 初始化配置
 func (c *Config) init(logger *Logger) {
 	c.logger = logger
 	if c.FatalHandling == nil {
 		c.FatalHandling = func(l *Logger, r any) {
-			l.Error("abnormal exits from panic: ", r, "\n", string(debug.Stack()))
-			l.Close()
-			os.Exit(1)
+			switch l.config.FatalAction {
+			case FatalPanic:
+				panic(r)
+			case FatalContinue:
+			default:
+				l.Close()
+				os.Exit(l.config.exitCode())
+			}
 		}
 	}
-	if c.GoExec == nil {
-		c.GoExec = func(f func()) {
-			go f()
+	if c.Executor == nil {
+		if c.GoExec != nil {
+			c.Executor = ExecFunc(c.GoExec)
+		} else {
+			c.Executor = defaultExecutor{}
 		}
 	}
-	if c.Level < LevelVerBose || c.Level > LevelFatal {
+	c.exec = newExecutor(c.Executor)
+	c.errs = &groErrors{}
+	c.hooks = append([]groHook(nil), c.hooks...)
+	if c.MsgCallback != nil {
+		callback := c.MsgCallback
+		c.hooks = append(c.hooks, newHook(HookFunc(func(r Record) error {
+			callback(r.Level, r.Message)
+			return nil
+		}), true, nil))
+	}
+	if c.Level < LevelVerBose || c.Level > LevelPanic {
 		c.Level = defaultLevel
 	}
+	if len(c.NamedLevels) > 0 {
+		levels := make(map[string]int, len(c.NamedLevels))
+		for name, level := range c.NamedLevels {
+			if name != "" && level >= LevelVerBose && level <= LevelPanic {
+				levels[name] = level
+			}
+		}
+		c.NamedLevels = levels
+	}
 	if c.Style < StyleBasic || c.Style > StyleDetail {
 		c.Style = defaultStyle
 	}
+	if c.TimeFormat == "" {
+		c.TimeFormat = defaultTimeFormat
+	}
+	loc, err := parseTimeZone(c.TimeZone)
+	if err != nil {
+		c.error(fmt.Errorf("time zone: %w", err))
+		c.TimeZone, loc = defaultTimeZone, time.Local
+	}
+	c.printTime = newTimeFormat(c.TimeFormat, loc)
+	c.saveTime = c.printTime
+	if c.SaveTimeFormat != "" && c.SaveTimeFormat != c.TimeFormat {
+		c.saveTime = newTimeFormat(c.SaveTimeFormat, loc)
+	}
+	if c.CallerFormat < CallerShort || c.CallerFormat > CallerFull {
+		c.CallerFormat = CallerShort
+	}
+	if c.StackLevel < LevelVerBose || c.StackLevel > LevelPanic {
+		c.StackLevel = defaultStackLevel
+	}
+	c.caller = &groCallerFormat{format: c.CallerFormat, trim: c.CallerTrim, function: c.EnableFunc}
+	if c.PrintTarget < PrintStdout || c.PrintTarget > PrintSplit {
+		c.PrintTarget = PrintStdout
+	}
+	if c.Color < ColorAuto || c.Color > ColorNever {
+		c.Color = ColorAuto
+	}
+	if c.PrintEscape < EscapeNone || c.PrintEscape > EscapeAll {
+		c.PrintEscape = EscapeNone
+	}
+	if c.SaveEscape < EscapeNone || c.SaveEscape > EscapeAll {
+		c.SaveEscape = EscapeNone
+	}
+	if c.FatalAction < FatalExit || c.FatalAction > FatalContinue {
+		c.FatalAction = defaultFatalAction
+	}
+	if c.ExitCode == 0 || c.ExitCode < ExitSuccess {
+		c.ExitCode = defaultExitCode
+	}
 	if c.MaxAsynExec <= 0 {
 		c.MaxAsynExec = defaultMaxAsynExec
 	}
@@ -177,9 +346,61 @@ func (c *Config) init(logger *Logger) {
 	if duration, err := time.ParseDuration(c.FlashInterval); err != nil || duration < 0 {
 		c.FlashInterval = defaultFlashInterval
 	}
+	if duration, err := time.ParseDuration(c.FlashLatency); err != nil || duration < 0 {
+		c.FlashLatency = defaultFlashLatency
+	}
 	if duration, err := time.ParseDuration(c.ExpireTime); err != nil || duration < 0 {
 		c.ExpireTime = defaultExpireTime
 	}
+	if duration, err := time.ParseDuration(c.RetryInterval); err != nil || duration < 0 {
+		c.RetryInterval = defaultRetryInterval
+	}
+	if c.FallbackMemory <= 0 {
+		c.FallbackMemory = defaultFallbackMemory
+	}
+	c.Fallback = append([]int(nil), c.Fallback...)
+	for _, out := range c.Fallback {
+		if out == FallbackMemory && c.memory == nil {
+			c.memory = newMemory(c.FallbackMemory)
+		}
+	}
+	if c.RedactMask == "" {
+		c.RedactMask = defaultRedactMask
+	}
+	c.redactor = newRedactor(c)
+	c.vmodule = newVModule(c)
+
+	if len(c.EncryptKey) > 0 && !c.DisableSave {
+		aead, err := newAEAD(c.EncryptKey)
+		if err != nil {
+			c.error(fmt.Errorf("encrypt key: %w, log file disabled", err))
+			c.DisableSave = true
+		}
+		c.aead = aead
+	}
+}
+
+// 获取致命错误退出码 (ExitSuccess 对应0)
+func (c *Config) exitCode() int {
+	if c.ExitCode == ExitSuccess {
+		return 0
+	}
+	return c.ExitCode
+}
+
+// 报告内部错误
+func (c *Config) error(err error) {
+	c.errs.Set(err)
+	c.handle(err)
+}
+
+// 调用内部错误处理函数 (错误已记录)
+func (c *Config) handle(err error) {
+	if c.ErrorHandler != nil {
+		c.ErrorHandler(err)
+	} else {
+		fmt.Fprintf(os.Stderr, "grolog: %v\n", err)
+	}
 }
 
 // 使用配置选项
@@ -189,6 +410,20 @@ func (c *Config) Use(opts ...Option) {
 	}
 }
 
+// 设置异步执行器
+func WithExecutor(exec Executor) Option {
+	return func(opt *Config) {
+		opt.Executor = exec
+	}
+}
+
+// 设置调用链提取器
+func WithSpanExtractor(extractor SpanExtractor) Option {
+	return func(opt *Config) {
+		opt.SpanExtractor = extractor
+	}
+}
+
 // 设置异常日志处理
 func WithFatalHandling(handling func(*Logger, any)) Option {
 	return func(opt *Config) {
@@ -196,13 +431,39 @@ func WithFatalHandling(handling func(*Logger, any)) Option {
 	}
 }
 
+// 设置内部错误处理函数
+func WithErrorHandler(handler func(error)) Option {
+	return func(opt *Config) {
+		opt.ErrorHandler = handler
+	}
+}
+
 // 设置日志消息回调
+//
+// Deprecated: 使用 WithAsynHook 代替.
 func WithMsgCallback(handler func(int, string)) Option {
 	return func(opt *Config) {
 		opt.MsgCallback = handler
 	}
 }
 
+// 添加日志钩子 (按日志顺序执行, 触发级别为空时所有级别均触发)
+//
+// 同步模式下在记录日志的协程中执行; 异步模式下在消费者协程之外的单个任务中依次执行, 致命日志等待推送完成时不等待钩子.
+// 钩子中可以记录日志、刷新或以 FatalContinue、FatalPanic 动作记录致命日志, 但不得关闭日志器 (包括默认的 FatalExit 动作).
+func WithHook(hook Hook, levels ...int) Option {
+	return func(opt *Config) {
+		opt.hooks = append(opt.hooks, newHook(hook, false, levels))
+	}
+}
+
+// 添加异步日志钩子 (通过异步执行器执行, 触发级别为空时所有级别均触发)
+func WithAsynHook(hook Hook, levels ...int) Option {
+	return func(opt *Config) {
+		opt.hooks = append(opt.hooks, newHook(hook, true, levels))
+	}
+}
+
 // 设置异步执行函数
 func WithGoExec(exec func(f func())) Option {
 	return func(opt *Config) {
@@ -217,6 +478,25 @@ func WithLevel(level int) Option {
 	}
 }
 
+// 设置命名日志器级别 (名称为点号分隔的完整名称, 子日志器未配置时继承该级别)
+func WithNamedLevel(name string, level int) Option {
+	return func(opt *Config) {
+		levels := make(map[string]int, len(opt.NamedLevels)+1)
+		for n, l := range opt.NamedLevels {
+			levels[n] = l
+		}
+		levels[name] = level
+		opt.NamedLevels = levels
+	}
+}
+
+// 设置调用位置级别 (逗号分隔的 模式=级别, 模式不含 / 时匹配文件名, 否则匹配路径末尾, 首个匹配的规则生效)
+func WithVModule(spec string) Option {
+	return func(opt *Config) {
+		opt.VModule = spec
+	}
+}
+
 // 设置日志样式
 func WithStyle(style int) Option {
 	return func(opt *Config) {
@@ -224,6 +504,20 @@ func WithStyle(style int) Option {
 	}
 }
 
+// 设置致命错误动作
+func WithFatalAction(action int) Option {
+	return func(opt *Config) {
+		opt.FatalAction = action
+	}
+}
+
+// 设置致命错误退出码 (为0时使用默认值, 以0退出时使用 ExitSuccess)
+func WithExitCode(code int) Option {
+	return func(opt *Config) {
+		opt.ExitCode = code
+	}
+}
+
 // 设置是否启用异步
 func WithEnableAsyn(asyn bool) Option {
 	return func(opt *Config) {
@@ -238,6 +532,27 @@ func WithEnableFileTime(enable bool) Option {
 	}
 }
 
+// 设置是否启用链式校验
+func WithEnableChain(enable bool) Option {
+	return func(opt *Config) {
+		opt.EnableChain = enable
+	}
+}
+
+// 设置链式校验密钥
+func WithChainKey(key []byte) Option {
+	return func(opt *Config) {
+		opt.ChainKey = key
+	}
+}
+
+// 设置日志文件加密密钥
+func WithEncryptKey(key []byte) Option {
+	return func(opt *Config) {
+		opt.EncryptKey = key
+	}
+}
+
 // 设置是否禁用日志文件
 func WithDisableSave(save bool) Option {
 	return func(opt *Config) {
@@ -252,7 +567,107 @@ func WithDisablePrint(print bool) Option {
 	}
 }
 
+// 设置时间格式 (Go 时间布局或 TimeUnix、TimeUnixMilli、TimeUnixMicro、TimeUnixNano)
+func WithTimeFormat(layout string) Option {
+	return func(opt *Config) {
+		opt.TimeFormat = layout
+	}
+}
+
+// 设置日志文件时间格式
+func WithSaveTimeFormat(layout string) Option {
+	return func(opt *Config) {
+		opt.SaveTimeFormat = layout
+	}
+}
+
+// 设置时区
+func WithTimeZone(zone string) Option {
+	return func(opt *Config) {
+		opt.TimeZone = zone
+	}
+}
+
+// 设置调用位置格式
+func WithCallerFormat(format int) Option {
+	return func(opt *Config) {
+		opt.CallerFormat = format
+	}
+}
+
+// 设置调用位置去除的路径前缀
+func WithCallerTrim(prefix string) Option {
+	return func(opt *Config) {
+		opt.CallerTrim = prefix
+	}
+}
+
+// 设置是否启用调用函数名称
+func WithEnableFunc(enable bool) Option {
+	return func(opt *Config) {
+		opt.EnableFunc = enable
+	}
+}
+
+// 设置是否在日志文件中写入调用位置
+func WithSaveCaller(enable bool) Option {
+	return func(opt *Config) {
+		opt.SaveCaller = enable
+	}
+}
+
+// 设置是否启用调用堆栈
+func WithEnableStack(enable bool) Option {
+	return func(opt *Config) {
+		opt.EnableStack = enable
+	}
+}
+
+// 设置调用堆栈级别
+func WithStackLevel(level int) Option {
+	return func(opt *Config) {
+		opt.StackLevel = level
+	}
+}
+
+// 设置日志打印目标
+func WithPrintTarget(target int) Option {
+	return func(opt *Config) {
+		opt.PrintTarget = target
+	}
+}
+
+// 设置日志打印颜色模式
+func WithColor(mode int) Option {
+	return func(opt *Config) {
+		opt.Color = mode
+	}
+}
+
+// 设置日志级别颜色 (日志级别到 SGR 参数, 如 "31;1", 为空字符串时不着色)
+func WithColorPalette(palette map[int]string) Option {
+	return func(opt *Config) {
+		opt.ColorPalette = palette
+	}
+}
+
+// 设置日志打印转义方式
+func WithPrintEscape(escape int) Option {
+	return func(opt *Config) {
+		opt.PrintEscape = escape
+	}
+}
+
+// 设置日志文件转义方式
+func WithSaveEscape(escape int) Option {
+	return func(opt *Config) {
+		opt.SaveEscape = escape
+	}
+}
+
 // 设置异步执行数量上限
+//
+// Deprecated: 异步模式固定使用单个消费者, 该选项不再生效.
 func WithAsynMaxGor(max int) Option {
 	return func(opt *Config) {
 		opt.MaxAsynExec = max
@@ -308,9 +723,80 @@ func WithFlashInterval(interval string) Option {
 	}
 }
 
+// 设置日志文件最大缓冲时长
+func WithFlashLatency(latency string) Option {
+	return func(opt *Config) {
+		opt.FlashLatency = latency
+	}
+}
+
+// 设置日志文件重新打开间隔
+func WithRetryInterval(interval string) Option {
+	return func(opt *Config) {
+		opt.RetryInterval = interval
+	}
+}
+
+// 设置备用输出链
+func WithFallback(chain ...int) Option {
+	return func(opt *Config) {
+		opt.Fallback = chain
+	}
+}
+
+// 设置内存备用缓冲大小
+func WithFallbackMemory(size int) Option {
+	return func(opt *Config) {
+		opt.FallbackMemory = size
+	}
+}
+
+// 设置内置脱敏规则
+func WithRedact(rules int) Option {
+	return func(opt *Config) {
+		opt.Redact = rules
+	}
+}
+
+// 设置自定义脱敏正则表达式
+func WithRedactPatterns(patterns ...string) Option {
+	return func(opt *Config) {
+		opt.RedactPatterns = patterns
+	}
+}
+
+// 设置脱敏字段名称
+func WithRedactFields(names ...string) Option {
+	return func(opt *Config) {
+		opt.RedactFields = names
+	}
+}
+
+// 设置脱敏替换文本
+func WithRedactMask(mask string) Option {
+	return func(opt *Config) {
+		opt.RedactMask = mask
+	}
+}
+
 // 设置日志文件过期时间
 func WithExpireTime(expire string) Option {
 	return func(opt *Config) {
 		opt.ExpireTime = expire
 	}
 }
+
+// 获取命名日志器的级别 (依次查找名称及其祖先, 均未配置时使用日志级别)
+func (c *Config) namedLevel(name string) int {
+	for name != "" {
+		if level, ok := c.NamedLevels[name]; ok {
+			return level
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return c.Level
+}
@@ -0,0 +1,41 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"os"
+)
+
+const (
+	PrintStdout int = iota // 打印到标准输出 (默认)
+	PrintStderr            // 打印到标准错误
+	PrintSplit             // 警告及以上级别打印到标准错误, 其余打印到标准输出
+)
+
+// 打印输出
+type groConsole struct {
+	out    *os.File // 输出文件
+	colors []string // 日志级别颜色 (禁用颜色时为空)
+}
+
+// 创建打印输出
+func newConsole(c *Config, out *os.File) *groConsole {
+	return &groConsole{out: out, colors: newColors(c, out)}
+}
+
+// 创建打印输出 (返回低于警告级别和警告及以上级别的打印输出, 相同时为同一对象)
+func newConsoles(c *Config) (*groConsole, *groConsole) {
+	switch c.PrintTarget {
+	case PrintStderr:
+		stderr := newConsole(c, os.Stderr)
+		return stderr, stderr
+	case PrintSplit:
+		return newConsole(c, os.Stdout), newConsole(c, os.Stderr)
+	default:
+		stdout := newConsole(c, os.Stdout)
+		return stdout, stdout
+	}
+}
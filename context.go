@@ -0,0 +1,120 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// 上下文中保存日志器的键
+type loggerKey struct{}
+
+// 上下文中保存附加字段的键
+type fieldsKey struct{}
+
+// 丢弃日志的日志器 (上下文中没有日志器时使用, 首次使用时创建, 不打开日志文件也不启动后台任务)
+//
+// 所有级别均不记录; 致命日志和异常日志仍执行默认的致命错误动作和抛出异常.
+var discardLogger = sync.OnceValue(func() *Logger {
+	l := &Logger{config: DefaultConfig()}
+	l.config.DisablePrint = true
+	l.config.DisableSave = true
+	l.config.init(l)
+	l.handler = groHandlerDiscard{}
+	l.level = LevelPanic + 1
+	return l
+})
+
+// 日志处理器-丢弃
+type groHandlerDiscard struct{}
+
+var _ groHandler = groHandlerDiscard{}
+
+func (groHandlerDiscard) Flush() {}
+
+func (groHandlerDiscard) Sync() {}
+
+func (groHandlerDiscard) Close() {}
+
+func (groHandlerDiscard) Log(l *Logger, level int, layer int, a ...any) {}
+
+func (groHandlerDiscard) Logln(l *Logger, level int, layer int, a ...any) {}
+
+func (groHandlerDiscard) Logf(l *Logger, level int, layer int, format string, args ...any) {}
+
+func (groHandlerDiscard) Logw(l *Logger, level int, layer int, buf *groFieldBuf, msg string) {
+	putFieldBuf(buf)
+}
+
+// 返回保存了当前日志器的上下文
+func (l *Logger) WithContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// 获取上下文中保存的日志器 (上下文为空或未保存时返回丢弃日志的日志器, 需要默认日志器时在根上下文中保存)
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey{}).(*Logger); ok {
+			return l
+		}
+	}
+	return discardLogger()
+}
+
+// 返回附加了字段的上下文 (追加到上下文中已有的字段之后, 带上下文的日志方法记录这些字段)
+func ContextWithFields(ctx context.Context, fields ...Field) context.Context {
+	parent := contextFields(ctx)
+	merged := make([]Field, 0, len(parent)+len(fields))
+	merged = append(merged, parent...)
+	for _, f := range fields {
+		if f.kind == fieldBytes { // 字节切片字段可能被调用方修改, 复制数据
+			f.str = strings.Clone(f.str)
+		}
+		merged = append(merged, f)
+	}
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// 获取上下文中的附加字段 (创建后不再修改)
+func contextFields(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).([]Field)
+	return fields
+}
+
+func (l *Logger) VerBoseCtx(ctx context.Context, msg string, fields ...Field) {
+	l.logw(ctx, LevelVerBose, msg, fields)
+}
+
+func (l *Logger) DebugCtx(ctx context.Context, msg string, fields ...Field) {
+	l.logw(ctx, LevelDebug, msg, fields)
+}
+
+func (l *Logger) TraceCtx(ctx context.Context, msg string, fields ...Field) {
+	l.logw(ctx, LevelTrace, msg, fields)
+}
+
+func (l *Logger) WarningCtx(ctx context.Context, msg string, fields ...Field) {
+	l.logw(ctx, LevelWarning, msg, fields)
+}
+
+func (l *Logger) ErrorCtx(ctx context.Context, msg string, fields ...Field) {
+	l.logw(ctx, LevelError, msg, fields)
+}
+
+func (l *Logger) FatalCtx(ctx context.Context, msg string, fields ...Field) {
+	l.logw(ctx, LevelFatal, msg, fields)
+	l.fatal(msg)
+}
+
+func (l *Logger) PanicCtx(ctx context.Context, msg string, fields ...Field) {
+	l.logw(ctx, LevelPanic, msg, fields)
+	l.panic(msg)
+}
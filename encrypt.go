@@ -0,0 +1,288 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// 加密格式
+//
+// 加密的日志文件以 encryptMagic 和16字节随机文件标识开始, 之后为若干数据块, 每次写入文件生成一个数据块:
+// 4字节块长度 (大端序, 不含块长度和块尾) + 8字节块序号 (大端序, 从0开始) + 12字节随机数 + AES-GCM 密文 + 4字节块尾 (与块长度相同)。
+// 附加认证数据为文件标识和块序号, 删除、重排数据块或在文件之间移动数据块时认证失败 (删除末尾的数据块无法检测)。
+// 数据块独立认证, 写入中断时只丢失最后不完整的数据块; 块尾用于从文件末尾向前读取数据块。
+const encryptMagic = "GROLOGE2"
+
+const (
+	encryptIDSize   = 16                                // 文件标识长度
+	encryptHeadSize = len(encryptMagic) + encryptIDSize // 文件头长度
+	maxEncryptChunk = 64 * MiB                          // 数据块长度上限
+)
+
+// 未加密的日志文件
+var errNotEncrypted = errors.New("not an encrypted log file")
+
+// 创建 AES-GCM 加密算法 (密钥长度为16、24或32字节)
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// 加密文件状态 (追加写入时从文件末尾恢复)
+type groEncryptState struct {
+	id    [encryptIDSize]byte // 文件标识
+	index uint64              // 下一个数据块序号
+	ad    [encryptIDSize + 8]byte
+}
+
+// 写入加密文件头 (生成新的文件标识)
+func writeEncryptHeader(w io.Writer) (groEncryptState, error) {
+	var st groEncryptState
+	if _, err := rand.Read(st.id[:]); err != nil {
+		return st, err
+	}
+	if _, err := w.Write(append([]byte(encryptMagic), st.id[:]...)); err != nil {
+		return st, err
+	}
+	return st, nil
+}
+
+// 读取加密文件头
+func readEncryptHeader(b []byte) (groEncryptState, error) {
+	var st groEncryptState
+	if len(b) < encryptHeadSize || string(b[:len(encryptMagic)]) != encryptMagic {
+		return st, errNotEncrypted
+	}
+	copy(st.id[:], b[len(encryptMagic):])
+	return st, nil
+}
+
+// 数据块的附加认证数据 (文件标识 + 块序号)
+func (st *groEncryptState) aad(index uint64) []byte {
+	copy(st.ad[:], st.id[:])
+	binary.BigEndian.PutUint64(st.ad[encryptIDSize:], index)
+	return st.ad[:]
+}
+
+// 解密数据块 (chunk 为块序号、随机数和密文, 返回块序号和明文)
+func (st *groEncryptState) open(aead cipher.AEAD, chunk []byte) (uint64, []byte, error) {
+	index := binary.BigEndian.Uint64(chunk)
+	nonce, sealed := chunk[8:8+aead.NonceSize()], chunk[8+aead.NonceSize():]
+	plain, err := aead.Open(sealed[:0], nonce, sealed, st.aad(index))
+	return index, plain, err
+}
+
+// 加密写入器
+type groEncrypter struct {
+	aead  cipher.AEAD
+	out   io.Writer
+	state groEncryptState
+	buf   []byte
+}
+
+// 创建加密写入器 (从文件状态中的块序号继续写入)
+func newEncrypter(aead cipher.AEAD, out io.Writer, state groEncryptState) *groEncrypter {
+	return &groEncrypter{aead: aead, out: out, state: state}
+}
+
+// 加密写入数据块
+func (e *groEncrypter) Write(p []byte) (int, error) {
+	size := e.aead.NonceSize()
+	body := uint32(8 + size + len(p) + e.aead.Overhead())
+	e.buf = append(e.buf[:0], make([]byte, 12+size)...)
+	binary.BigEndian.PutUint32(e.buf, body)
+	binary.BigEndian.PutUint64(e.buf[4:], e.state.index)
+	nonce := e.buf[12:]
+	if _, err := rand.Read(nonce); err != nil {
+		return 0, err
+	}
+	e.buf = e.aead.Seal(e.buf, nonce, p, e.state.aad(e.state.index))
+	e.buf = binary.BigEndian.AppendUint32(e.buf, body)
+
+	if _, err := e.out.Write(e.buf); err != nil {
+		return 0, err
+	}
+	e.state.index++
+	return len(p), nil
+}
+
+// 解密读取器
+type groDecrypter struct {
+	aead  cipher.AEAD
+	in    *bufio.Reader
+	state groEncryptState // 文件标识和下一个数据块序号
+	chunk []byte          // 数据块缓冲区
+	plain []byte          // 未读取的明文
+	size  int64           // 已读取的完整数据块大小 (包含文件头)
+	err   error
+}
+
+// 创建解密读取器 (读取加密的日志文件, 最后不完整的数据块视为结束, 认证失败或数据块缺失、顺序错误时返回错误)
+func NewDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt key: %w", err)
+	}
+	return newDecrypter(r, aead)
+}
+
+// 创建解密读取器
+func newDecrypter(r io.Reader, aead cipher.AEAD) (*groDecrypter, error) {
+	d := &groDecrypter{aead: aead, in: bufio.NewReader(r)}
+
+	head := make([]byte, encryptHeadSize)
+	n, err := io.ReadFull(d.in, head)
+	switch {
+	case n == 0 && err == io.EOF:
+		d.err = io.EOF
+	case err != nil && err != io.ErrUnexpectedEOF:
+		return nil, err
+	default:
+		if d.state, err = readEncryptHeader(head[:n]); err != nil {
+			return nil, err
+		}
+		d.size = int64(n)
+	}
+	return d, nil
+}
+
+// 读取明文
+func (d *groDecrypter) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.err = d.next()
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// 解密下一个数据块
+func (d *groDecrypter) next() error {
+	var head [4]byte
+	if _, err := io.ReadFull(d.in, head[:]); err != nil {
+		return d.eof(err)
+	}
+	size := binary.BigEndian.Uint32(head[:])
+	if !validChunkSize(d.aead, size) {
+		return fmt.Errorf("decrypt log file: invalid chunk size %d at offset %d", size, d.size)
+	}
+	if cap(d.chunk) < int(size)+4 {
+		d.chunk = make([]byte, size+4)
+	}
+	d.chunk = d.chunk[:size+4]
+	if _, err := io.ReadFull(d.in, d.chunk); err != nil {
+		return d.eof(err)
+	}
+	if binary.BigEndian.Uint32(d.chunk[size:]) != size {
+		return fmt.Errorf("decrypt log file: invalid chunk trailer at offset %d", d.size)
+	}
+
+	index, plain, err := d.state.open(d.aead, d.chunk[:size])
+	if err != nil {
+		return fmt.Errorf("decrypt log file: chunk %d at offset %d: %w", d.state.index, d.size, err)
+	}
+	if index != d.state.index {
+		return fmt.Errorf("decrypt log file: chunk %d at offset %d, want chunk %d", index, d.size, d.state.index)
+	}
+	d.state.index++
+	d.plain = plain
+	d.size += int64(len(head) + len(d.chunk))
+	return nil
+}
+
+// 读取结束 (不完整的数据块视为结束)
+func (d *groDecrypter) eof(err error) error {
+	if err == io.ErrUnexpectedEOF {
+		return io.EOF
+	}
+	return err
+}
+
+// 数据块长度是否有效
+func validChunkSize(aead cipher.AEAD, size uint32) bool {
+	return size >= uint32(8+aead.NonceSize()+aead.Overhead()) && size <= maxEncryptChunk
+}
+
+// 读取加密的日志文件末尾的明文 (返回不少于 n 字节的末尾明文 (文件较小时为全部明文)、有效大小和文件状态)
+//
+// 从文件末尾向前逐个读取数据块; 最后的数据块不完整或无法向前读取时, 从文件开始解密全部数据块, 有效大小不含最后不完整的数据块.
+func readEncryptedTail(r io.ReaderAt, size int64, aead cipher.AEAD, n int) ([]byte, int64, groEncryptState, error) {
+	head := make([]byte, min(size, int64(encryptHeadSize)))
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return nil, 0, groEncryptState{}, err
+	}
+	state, err := readEncryptHeader(head)
+	if err != nil {
+		return nil, 0, state, err
+	}
+
+	// 从文件末尾向前读取
+	var chunks [][]byte
+	var prev uint64
+	total, end := 0, size
+	for end > int64(encryptHeadSize) && total < n {
+		var trailer [4]byte
+		if _, err := r.ReadAt(trailer[:], end-4); err != nil {
+			break
+		}
+		chunkSize := binary.BigEndian.Uint32(trailer[:])
+		start := end - 8 - int64(chunkSize)
+		if !validChunkSize(aead, chunkSize) || start < int64(encryptHeadSize) {
+			break
+		}
+		chunk := make([]byte, 4+chunkSize)
+		if _, err := r.ReadAt(chunk, start); err != nil || binary.BigEndian.Uint32(chunk) != chunkSize {
+			break
+		}
+		index, plain, err := state.open(aead, chunk[4:])
+		if err != nil || (len(chunks) > 0 && index+1 != prev) {
+			break
+		}
+		if len(chunks) == 0 {
+			state.index = index + 1
+		}
+		chunks = append(chunks, plain)
+		prev, total, end = index, total+len(plain), start
+	}
+	if end == int64(encryptHeadSize) || total >= n {
+		tail := make([]byte, 0, total)
+		for i := len(chunks) - 1; i >= 0; i-- {
+			tail = append(tail, chunks[i]...)
+		}
+		return tail[max(0, len(tail)-n):], size, state, nil
+	}
+
+	// 从文件开始解密
+	d, err := newDecrypter(io.NewSectionReader(r, 0, size), aead)
+	if err != nil {
+		return nil, 0, state, err
+	}
+	var tail []byte
+	for {
+		if err := d.next(); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, 0, state, err
+		}
+		tail = append(tail, d.plain...)
+		tail = tail[max(0, len(tail)-n):]
+	}
+	return tail, d.size, d.state, nil
+}
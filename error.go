@@ -0,0 +1,31 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"sync"
+)
+
+// 内部错误记录
+type groErrors struct {
+	lock sync.Mutex
+	last error // 最近一次内部错误
+}
+
+// 记录内部错误
+func (e *groErrors) Set(err error) {
+	e.lock.Lock()
+	e.last = err
+	e.lock.Unlock()
+}
+
+// 获取最近一次内部错误
+func (e *groErrors) Get() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return e.last
+}
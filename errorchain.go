@@ -0,0 +1,98 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+)
+
+// 附带字段的错误 (记录日志时字段附加到日志消息)
+type ErrorFielder interface {
+	ErrorFields() []Field
+}
+
+// 附带调用堆栈的错误 (记录日志时调用堆栈附加到日志消息, 优先于自动调用堆栈)
+type ErrorStacker interface {
+	Callers() []uintptr
+}
+
+// 获取日志参数或附加字段中的首个错误
+func findError(a []any, fields []Field) error {
+	for _, v := range a {
+		if err, ok := v.(error); ok {
+			return err
+		}
+	}
+	for _, f := range fields {
+		if err, ok := f.Value.(error); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// 遍历错误链 (深度优先, 包括 errors.Join 的多个错误, depth 为嵌套深度)
+func walkError(err error, depth int, f func(err error, depth int)) {
+	if err == nil {
+		return
+	}
+	f(err, depth)
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		walkError(e.Unwrap(), depth+1, f)
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			walkError(inner, depth+1, f)
+		}
+	}
+}
+
+// 展开错误 (附加错误链中的字段, 使用最内层的调用堆栈, 需要时生成错误链)
+func (m *groMsg) expandError(chain bool) {
+	var fields []Field
+	var callers []uintptr
+	m.causes = m.causes[:0]
+	walkError(m.err, 0, func(err error, depth int) {
+		if e, ok := err.(ErrorFielder); ok {
+			fields = append(fields, e.ErrorFields()...)
+		}
+		if e, ok := err.(ErrorStacker); ok {
+			callers = e.Callers()
+		}
+		if chain {
+			m.causes = append(m.causes, errorCause(err, depth))
+		}
+	})
+
+	if len(fields) > 0 {
+		// 附加字段与日志器共享, 修改前复制
+		m.fields = append(append(make([]Field, 0, len(m.fields)+len(fields)), m.fields...), fields...)
+	}
+	if len(callers) > 0 {
+		m.trace = append(m.trace[:0], callers...)
+	}
+}
+
+// 生成错误链中的一项 (格式: 类型: 消息, 按嵌套深度缩进)
+func errorCause(err error, depth int) string {
+	cause := strings.Repeat("  ", depth)
+	if depth > 0 {
+		cause += "caused by "
+	}
+	return cause + reflect.TypeOf(err).String() + ": " + err.Error()
+}
+
+// 写入错误链 (作为消息正文的后续行, 每个错误一行, 错误消息中的换行始终转义)
+func writeErrorChain(buf *bytes.Buffer, causes []string, escape int) {
+	newline := escapedNewline(escape)
+	for _, cause := range causes {
+		buf.WriteString(newline)
+		buf.WriteString("\t")
+		writeEscaped(buf, []byte(cause), max(escape, EscapeAll))
+	}
+}
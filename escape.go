@@ -0,0 +1,70 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"bytes"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	EscapeNone    int = iota // 原样输出 (默认)
+	EscapeControl            // 转义控制字符, 消息内的换行保留并在续行前缩进 (多行堆栈保持可读, 无法伪造新的日志行)
+	EscapeAll                // 转义控制字符和换行, 每条日志只占一行
+)
+
+// 续行缩进
+const escapeIndent = "\t"
+
+// 写入转义后的消息 (结尾换行作为日志行结束符保留)
+func writeEscaped(buf *bytes.Buffer, text []byte, escape int) {
+	if escape == EscapeNone {
+		buf.Write(text)
+		return
+	}
+
+	newline := len(text) > 0 && text[len(text)-1] == '\n'
+	if newline {
+		text = text[:len(text)-1]
+	}
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		switch {
+		case r == '\n' && escape == EscapeControl:
+			buf.WriteByte('\n')
+			buf.WriteString(escapeIndent)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\t':
+			buf.WriteByte('\t')
+		case r == utf8.RuneError && size == 1:
+			buf.WriteString(`\x`)
+			buf.WriteString(strconv.FormatUint(uint64(text[0])|0x100, 16)[1:])
+		case unicode.IsControl(r) || r == '\u2028' || r == '\u2029':
+			quoted := strconv.QuoteRuneToASCII(r)
+			buf.WriteString(quoted[1 : len(quoted)-1])
+		default:
+			buf.Write(text[:size])
+		}
+		text = text[size:]
+	}
+	if newline {
+		buf.WriteByte('\n')
+	}
+}
+
+// 转义后的消息内换行
+func escapedNewline(escape int) string {
+	switch escape {
+	case EscapeControl:
+		return "\n" + escapeIndent
+	case EscapeAll:
+		return `\n`
+	}
+	return "\n"
+}
@@ -7,7 +7,6 @@ package main
 
 import (
 	"os"
-	"runtime/debug"
 	"time"
 
 	"github.com/tayne3/grolog"
@@ -16,7 +15,7 @@ import (
 var logger *grolog.Logger
 
 func ExDebug(a ...any) {
-	logger.Caller(1).Debug(a...)
+	logger.WithCallerSkip(1).Debug(a...)
 }
 
 func main() {
@@ -24,17 +23,16 @@ func main() {
 	logger = grolog.New(nil,
 		grolog.WithLevel(grolog.LevelVerBose),
 		grolog.WithStyle(grolog.StyleBrief),
-		grolog.WithMsgCallback(nil),
 		grolog.WithGoExec(func(f func()) {
 			go f()
 		}),
 		grolog.WithFatalHandling(func(l *grolog.Logger, r any) { // 重定向异常处理
-			l.Error("abnormal exits from panic: ", r, "\n", string(debug.Stack()))
 			l.Close()
 			os.Exit(1)
 		}),
 	)
 	defer logger.Close()
+	defer logger.Recover() // 捕获异常
 
 	for i := 0; i < 6; i++ {
 		switch i {
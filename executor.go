@@ -0,0 +1,100 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"sync"
+)
+
+// 后台任务名称
+const (
+	TaskConsumer = "grolog.consumer" // 异步消费者 (常驻, 日志器关闭时结束)
+	TaskFlusher  = "grolog.flusher"  // 定时刷新 (常驻, 日志器关闭时结束)
+	TaskCleaner  = "grolog.cleaner"  // 过期文件清理
+	TaskCallback = "grolog.callback" // 异步日志钩子
+)
+
+// 异步执行器
+//
+// 日志器的全部后台任务均通过执行器运行, 执行器必须保证每个任务最终都会被执行;
+// 常驻任务会一直占用执行协程, 直到日志器关闭.
+type Executor interface {
+	Go(task string, f func()) // 执行后台任务
+}
+
+// 执行器生命周期钩子 (可选, 由 Executor 实现)
+type ExecutorHooks interface {
+	OnStart() // 日志器启动后台任务前调用
+	OnStop()  // 日志器关闭且全部后台任务结束后调用
+}
+
+// 函数执行器
+type ExecFunc func(f func())
+
+// 执行后台任务
+func (e ExecFunc) Go(_ string, f func()) {
+	e(f)
+}
+
+// 默认执行器 (使用go语句执行)
+type defaultExecutor struct{}
+
+// 执行后台任务
+func (defaultExecutor) Go(_ string, f func()) {
+	go f()
+}
+
+// 日志器执行器 (跟踪后台任务, 关闭时等待全部任务结束)
+type groExecutor struct {
+	exec   Executor       // 执行器
+	once   sync.Once      // 关闭保护
+	wait   sync.WaitGroup // 任务等待
+	mutex  sync.Mutex     // 关闭标志锁 (保证关闭后不再添加等待的任务)
+	closed bool           // 是否已关闭
+}
+
+// 创建日志器执行器
+func newExecutor(exec Executor) *groExecutor {
+	return &groExecutor{exec: exec}
+}
+
+// 启动执行器
+func (e *groExecutor) Start() {
+	if hooks, ok := e.exec.(ExecutorHooks); ok {
+		hooks.OnStart()
+	}
+}
+
+// 关闭执行器 (等待全部后台任务结束)
+func (e *groExecutor) Close() {
+	e.mutex.Lock()
+	e.closed = true
+	e.mutex.Unlock()
+
+	e.wait.Wait()
+	e.once.Do(func() {
+		if hooks, ok := e.exec.(ExecutorHooks); ok {
+			hooks.OnStop()
+		}
+	})
+}
+
+// 执行后台任务 (关闭后在调用协程中直接执行)
+func (e *groExecutor) Go(task string, f func()) {
+	e.mutex.Lock()
+	if e.closed {
+		e.mutex.Unlock()
+		f()
+		return
+	}
+	e.wait.Add(1)
+	e.mutex.Unlock()
+
+	e.exec.Go(task, func() {
+		defer e.wait.Done()
+		f()
+	})
+}
@@ -0,0 +1,94 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"os"
+	"sync"
+)
+
+const (
+	FallbackStderr int = iota // 备用输出到标准错误
+	FallbackMemory            // 备用输出到内存环形缓冲区 (保留最近的日志)
+)
+
+// 备用输出 (日志文件不可用时按顺序尝试, 直到写入成功)
+type groFallback struct {
+	chain  []int      // 备用输出链
+	memory *groMemory // 内存环形缓冲区 (未启用时为空)
+}
+
+// 创建备用输出
+func newFallback(chain []int, memory *groMemory) *groFallback {
+	return &groFallback{chain: chain, memory: memory}
+}
+
+// 写入日志 (全部备用输出均失败时丢弃)
+func (f *groFallback) Write(b []byte) {
+	for _, out := range f.chain {
+		switch out {
+		case FallbackStderr:
+			if _, err := os.Stderr.Write(b); err == nil {
+				return
+			}
+		case FallbackMemory:
+			f.memory.Write(b)
+			return
+		}
+	}
+}
+
+// 内存环形缓冲区 (按日志行保存, 超出容量时丢弃最早的日志)
+type groMemory struct {
+	lock  sync.Mutex
+	lines [][]byte // 日志行
+	head  int      // 最早的日志行位置
+	size  int      // 已保存的字节数
+	limit int      // 容量上限 (字节)
+}
+
+// 创建内存环形缓冲区
+func newMemory(limit int) *groMemory {
+	return &groMemory{limit: limit}
+}
+
+// 写入日志行
+func (m *groMemory) Write(b []byte) {
+	if len(b) > m.limit {
+		b = b[len(b)-m.limit:]
+	}
+	line := append([]byte(nil), b...)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.lines = append(m.lines, line)
+	m.size += len(line)
+	for m.size > m.limit {
+		m.size -= len(m.lines[m.head])
+		m.lines[m.head] = nil
+		m.head++
+	}
+	// 已丢弃的位置过多时压缩
+	if m.head > len(m.lines)/2 {
+		n := copy(m.lines, m.lines[m.head:])
+		clear(m.lines[n:])
+		m.lines = m.lines[:n]
+		m.head = 0
+	}
+}
+
+// 读取全部日志
+func (m *groMemory) Bytes() []byte {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	b := make([]byte, 0, m.size)
+	for _, line := range m.lines[m.head:] {
+		b = append(b, line...)
+	}
+	return b
+}
@@ -0,0 +1,303 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// 字段类型
+const (
+	fieldAny      = iota // 任意类型 (值保存在 Value)
+	fieldInt             // 整数
+	fieldString          // 字符串
+	fieldBytes           // 字节切片 (以字符串保存, 记录日志时复制)
+	fieldBool            // 布尔
+	fieldDuration        // 时长
+	fieldTime            // 时间 (Value 保存时区, 超出纳秒时间戳范围时保存时间值)
+)
+
+// 日志字段
+type Field struct {
+	Key   string // 字段名称
+	Value any    // 字段值 (类型化字段的值由 kind 决定, 日志记录中的字段总是有值)
+	kind  int    // 字段类型
+	num   int64  // 整数、布尔、时长和时间 (纳秒) 字段的值
+	str   string // 字符串和字节切片字段的值
+}
+
+// 创建任意类型字段
+func Any(key string, value any) Field {
+	return Field{Key: key, Value: value}
+}
+
+// 创建整数字段 (不分配内存)
+func Int(key string, value int) Field {
+	return Field{Key: key, kind: fieldInt, num: int64(value)}
+}
+
+// 创建64位整数字段 (不分配内存)
+func Int64(key string, value int64) Field {
+	return Field{Key: key, kind: fieldInt, num: value}
+}
+
+// 创建字符串字段 (不分配内存)
+func Str(key string, value string) Field {
+	return Field{Key: key, kind: fieldString, str: value}
+}
+
+// 创建字节切片字段 (不分配内存, 以字符串输出, 调用返回前不得修改)
+func Bytes(key string, value []byte) Field {
+	return Field{Key: key, kind: fieldBytes, str: unsafe.String(unsafe.SliceData(value), len(value))}
+}
+
+// 创建布尔字段 (不分配内存)
+func Bool(key string, value bool) Field {
+	f := Field{Key: key, kind: fieldBool}
+	if value {
+		f.num = 1
+	}
+	return f
+}
+
+// 创建时长字段 (不分配内存)
+func Dur(key string, value time.Duration) Field {
+	return Field{Key: key, kind: fieldDuration, num: int64(value)}
+}
+
+// 纳秒时间戳的表示范围 (1678年至2262年)
+var (
+	minNanoTime = time.Unix(0, math.MinInt64)
+	maxNanoTime = time.Unix(0, math.MaxInt64)
+)
+
+// 创建时间字段 (以 RFC3339Nano 格式输出, 超出纳秒时间戳范围时保存时间值并分配内存, 否则不分配内存)
+func Time(key string, value time.Time) Field {
+	if value.Before(minNanoTime) || value.After(maxNanoTime) {
+		return Field{Key: key, kind: fieldTime, Value: value}
+	}
+	return Field{Key: key, kind: fieldTime, num: value.UnixNano(), Value: value.Location()}
+}
+
+// 获取字段值 (类型化字段转换为对应类型, 字符串和字节切片字段可能引用字段缓冲区, 复制数据)
+func (f Field) value() any {
+	switch f.kind {
+	case fieldInt:
+		return f.num
+	case fieldString:
+		return strings.Clone(f.str)
+	case fieldBytes:
+		return []byte(f.str)
+	case fieldBool:
+		return f.num != 0
+	case fieldDuration:
+		return time.Duration(f.num)
+	case fieldTime:
+		return f.time()
+	}
+	return f.Value
+}
+
+// 获取时间字段的值
+func (f Field) time() time.Time {
+	if t, ok := f.Value.(time.Time); ok {
+		return t
+	}
+	t := time.Unix(0, f.num)
+	if loc, ok := f.Value.(*time.Location); ok {
+		t = t.In(loc)
+	}
+	return t
+}
+
+// 获取字符串值 (字符串、字节切片、错误和 fmt.Stringer 字段, 其他字段返回false)
+func (f Field) string() (string, bool) {
+	switch f.kind {
+	case fieldString, fieldBytes:
+		return f.str, true
+	case fieldAny:
+		switch v := f.Value.(type) {
+		case string:
+			return v, true
+		case error:
+			return v.Error(), true
+		case fmt.Stringer:
+			return v.String(), true
+		}
+	}
+	return "", false
+}
+
+// 写入字段 (格式: key=value, 字段名称和值包含空白或特殊字符时加引号并转义)
+func writeField(buf *bytes.Buffer, f Field) {
+	writeFieldString(buf, f.Key)
+	buf.WriteByte('=')
+	switch f.kind {
+	case fieldInt:
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), f.num, 10))
+		return
+	case fieldBool:
+		buf.Write(strconv.AppendBool(buf.AvailableBuffer(), f.num != 0))
+		return
+	case fieldDuration:
+		buf.Write(appendDuration(buf.AvailableBuffer(), time.Duration(f.num)))
+		return
+	case fieldTime:
+		buf.Write(f.time().AppendFormat(buf.AvailableBuffer(), time.RFC3339Nano))
+		return
+	}
+	if s, ok := f.string(); ok {
+		writeFieldString(buf, s)
+		return
+	}
+	writeFieldString(buf, fmt.Sprint(f.Value))
+}
+
+// 写入字段字符串值 (包含空白、控制字符或特殊字符时加引号并转义)
+func writeFieldString(buf *bytes.Buffer, s string) {
+	if s == "" || strings.IndexFunc(s, fieldNeedQuote) >= 0 {
+		buf.Write(strconv.AppendQuote(buf.AvailableBuffer(), s))
+		return
+	}
+	buf.WriteString(s)
+}
+
+// 字段字符串值是否需要加引号
+func fieldNeedQuote(r rune) bool {
+	return r == '=' || r == '"' || r == utf8.RuneError || unicode.IsSpace(r) || unicode.IsControl(r)
+}
+
+// 追加时长 (与 time.Duration.String 格式相同, 不分配内存)
+func appendDuration(b []byte, d time.Duration) []byte {
+	if d == 0 {
+		return append(b, "0s"...)
+	}
+	u := uint64(d)
+	if d < 0 {
+		b = append(b, '-')
+		u = -u
+	}
+	if u < uint64(time.Second) {
+		// 小于1秒时使用 ns、µs 或 ms
+		switch {
+		case u < uint64(time.Microsecond):
+			return append(strconv.AppendUint(b, u, 10), "ns"...)
+		case u < uint64(time.Millisecond):
+			return append(appendFrac(b, u, 3), "µs"...)
+		default:
+			return append(appendFrac(b, u, 6), "ms"...)
+		}
+	}
+
+	// 大于等于1秒时使用 h、m 和带小数的 s
+	if hours := u / uint64(time.Hour); hours > 0 {
+		b = append(strconv.AppendUint(b, hours, 10), 'h')
+		u -= hours * uint64(time.Hour)
+		b = append(strconv.AppendUint(b, u/uint64(time.Minute), 10), 'm')
+		u %= uint64(time.Minute)
+	} else if minutes := u / uint64(time.Minute); minutes > 0 {
+		b = append(strconv.AppendUint(b, minutes, 10), 'm')
+		u %= uint64(time.Minute)
+	}
+	return append(appendFrac(b, u, 9), 's')
+}
+
+// 追加 v/10^prec 的小数形式 (去除结尾的0)
+func appendFrac(b []byte, v uint64, prec int) []byte {
+	unit := pow10(prec)
+	b = strconv.AppendUint(b, v/unit, 10)
+	frac := v % unit
+	if frac == 0 {
+		return b
+	}
+	for frac%10 == 0 {
+		frac /= 10
+		prec--
+	}
+	b = append(b, '.')
+	for p := pow10(prec - 1); frac < p; p /= 10 {
+		b = append(b, '0')
+	}
+	return strconv.AppendUint(b, frac, 10)
+}
+
+// 10的n次方
+func pow10(n int) uint64 {
+	p := uint64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
+
+// 单次日志调用的字段缓冲区 (对象池复用)
+//
+// 字段值在格式化日志时直接写入 groBufferPool 的行缓冲区; 字段缓冲区保存的是调用返回后仍需使用的字段切片和字节切片数据
+// (异步模式下由消费者格式化), 不是 bytes.Buffer, 并且在日志器级别获取 (此时尚未确定推送器), 因此使用单独的对象池.
+type groFieldBuf struct {
+	fields []Field  // 字段
+	data   []byte   // 字节切片字段的数据
+	span   [50]byte // 调用链字段的数据
+}
+
+// 字段缓冲区对象池
+var fieldBufPool = sync.Pool{
+	New: func() any { return new(groFieldBuf) },
+}
+
+// 获取字段缓冲区
+func getFieldBuf() *groFieldBuf {
+	return fieldBufPool.Get().(*groFieldBuf)
+}
+
+// 添加字段 (字段创建后不再修改, 不复制数据)
+func (fb *groFieldBuf) append(fields []Field) {
+	fb.fields = append(fb.fields, fields...)
+}
+
+// 添加调用字段 (字节切片字段的数据复制到缓冲区)
+func (fb *groFieldBuf) appendCopy(fields []Field) {
+	start := len(fb.fields)
+	fb.fields = append(fb.fields, fields...)
+
+	size := 0
+	for _, f := range fields {
+		if f.kind == fieldBytes {
+			size += len(f.str)
+		}
+	}
+	if size == 0 {
+		return
+	}
+	if cap(fb.data) < size {
+		fb.data = make([]byte, 0, size)
+	}
+	fb.data = fb.data[:0]
+	for i := start; i < len(fb.fields); i++ {
+		f := &fb.fields[i]
+		if f.kind == fieldBytes && len(f.str) > 0 {
+			n := len(fb.data)
+			fb.data = append(fb.data, f.str...)
+			f.str = unsafe.String(&fb.data[n], len(f.str))
+		}
+	}
+}
+
+// 回收字段缓冲区
+func putFieldBuf(fb *groFieldBuf) {
+	clear(fb.fields)
+	fb.fields = fb.fields[:0]
+	fieldBufPool.Put(fb)
+}
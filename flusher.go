@@ -0,0 +1,138 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"sync"
+	"time"
+)
+
+// 定时刷新器
+//
+// 定时刷新器以固定间隔刷新缓冲区, 缓冲区因其他原因被刷新时 (手动刷新、缓冲区写满、文件切换等) 重新计时;
+// 同时保证缓冲区中的数据在超过最大缓冲时长之前被刷新.
+type groFlusher struct {
+	interval time.Duration  // 定时刷新间隔 (小于等于0时禁用)
+	latency  time.Duration  // 最大缓冲时长 (小于等于0时禁用)
+	exec     *groExecutor   // 日志器执行器
+	flush    func()         // 刷新函数
+	notify   chan struct{}  // 状态变化通知
+	mutex    sync.Mutex     // 状态锁
+	dirty    bool           // 待处理的写入通知
+	reset    bool           // 待处理的刷新通知 (先于写入通知处理)
+	done     chan struct{}  // 结束通知
+	once     sync.Once      // 结束保护
+	stop     sync.WaitGroup // 停止等待
+}
+
+// 创建定时刷新器
+func newFlusher(exec *groExecutor, interval time.Duration, latency time.Duration, flush func()) *groFlusher {
+	return &groFlusher{
+		interval: interval,
+		latency:  latency,
+		exec:     exec,
+		flush:    flush,
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+// 启动定时刷新器
+func (f *groFlusher) Start() {
+	if f.interval <= 0 && f.latency <= 0 {
+		return
+	}
+
+	f.stop.Add(1)
+	f.exec.Go(TaskFlusher, func() {
+		defer f.stop.Done()
+		f.run()
+	})
+}
+
+// 关闭定时刷新器
+func (f *groFlusher) Close() {
+	f.once.Do(func() {
+		close(f.done)
+	})
+	f.stop.Wait()
+}
+
+// 通知缓冲区有新数据写入
+func (f *groFlusher) Dirty() {
+	f.mutex.Lock()
+	f.dirty = true
+	f.mutex.Unlock()
+	f.wake()
+}
+
+// 通知缓冲区已刷新 (此前的写入已被刷新, 丢弃尚未处理的写入通知)
+func (f *groFlusher) Reset() {
+	f.mutex.Lock()
+	f.reset, f.dirty = true, false
+	f.mutex.Unlock()
+	f.wake()
+}
+
+// 唤醒刷新循环
+func (f *groFlusher) wake() {
+	select {
+	case f.notify <- struct{}{}:
+	default:
+	}
+}
+
+// 取出待处理的通知 (刷新通知与写入通知按发生顺序处理, 刷新不会取消为其后写入的数据开始的计时)
+func (f *groFlusher) take() (dirty bool, reset bool) {
+	f.mutex.Lock()
+	dirty, reset = f.dirty, f.reset
+	f.dirty, f.reset = false, false
+	f.mutex.Unlock()
+	return dirty, reset
+}
+
+// 刷新循环
+func (f *groFlusher) run() {
+	var tick <-chan time.Time // 定时刷新 (禁用时为空)
+	var ticker *time.Ticker
+	if f.interval > 0 {
+		ticker = time.NewTicker(f.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	var wait <-chan time.Time // 最大缓冲时长到期 (未计时时为空)
+	for {
+		select {
+		case <-f.done:
+			return
+		case <-tick:
+			f.flush()
+		case <-wait:
+			wait = nil
+			f.flush()
+		case <-f.notify:
+			dirty, reset := f.take()
+			if reset {
+				if ticker != nil {
+					ticker.Reset(f.interval)
+				}
+				if wait != nil {
+					timer.Stop()
+					wait = nil
+				}
+			}
+			if dirty && f.latency > 0 && wait == nil {
+				timer.Reset(f.latency)
+				wait = timer.C
+			}
+		}
+	}
+}
@@ -6,8 +6,10 @@
 package grolog
 
 import (
+	"context"
 	"sync"
 	"testing"
+	"time"
 )
 
 const (
@@ -58,3 +60,101 @@ func BenchmarkLoggerAsyn(b *testing.B) {
 	}
 	logger.Close()
 }
+
+// 创建字段日志基准测试的日志器 (写入临时目录的日志文件, 不打印)
+func newFieldsLogger(b *testing.B, opts ...Option) *Logger {
+	opts = append([]Option{
+		WithLevel(LevelTrace),
+		WithStyle(StyleBrief),
+		WithDisablePrint(true),
+		WithFileDir(b.TempDir()),
+		WithFileName("Bench"),
+	}, opts...)
+	return New(nil, opts...)
+}
+
+// 记录带类型化字段的日志
+func logFields(logger *Logger, payload []byte) {
+	logger.Tracew("request done",
+		Str("method", "GET"),
+		Int("status", 200),
+		Dur("elapsed", 1500*time.Microsecond),
+		Bool("cached", true),
+		Bytes("payload", payload),
+	)
+}
+
+func BenchmarkFieldsDisabled(b *testing.B) {
+	logger := newFieldsLogger(b, WithLevel(LevelWarning))
+	defer logger.Close()
+	payload := []byte("id=42")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logFields(logger, payload)
+	}
+}
+
+func BenchmarkFieldsSync(b *testing.B) {
+	logger := newFieldsLogger(b).With(Str("service", "bench"))
+	defer logger.Close()
+	payload := []byte("id=42")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logFields(logger, payload)
+	}
+}
+
+func BenchmarkFieldsAsyn(b *testing.B) {
+	logger := newFieldsLogger(b, WithEnableAsyn(true)).With(Str("service", "bench"))
+	defer logger.Close()
+	payload := []byte("id=42")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logFields(logger, payload)
+	}
+}
+
+func BenchmarkFieldsContext(b *testing.B) {
+	logger := newFieldsLogger(b)
+	defer logger.Close()
+	ctx := ContextWithFields(context.Background(), Str("request_id", "r1"), Str("tenant", "acme"))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.TraceCtx(ctx, "request done", Int("status", 200))
+	}
+}
+
+func BenchmarkFieldsSpan(b *testing.B) {
+	span := SpanContext{TraceID: [16]byte{1}, SpanID: [8]byte{2}, TraceFlags: 1}
+	logger := newFieldsLogger(b, WithSpanExtractor(SpanExtractorFunc(func(context.Context) (SpanContext, bool) {
+		return span, true
+	})))
+	defer logger.Close()
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.TraceCtx(ctx, "request done", Int("status", 200))
+	}
+}
+
+func BenchmarkFieldsVModule(b *testing.B) {
+	logger := newFieldsLogger(b, WithVModule("grolog_benchmark*=warning"))
+	defer logger.Close()
+	payload := []byte("id=42")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logFields(logger, payload)
+	}
+}
@@ -6,11 +6,21 @@
 package grolog
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -29,19 +39,20 @@ func TestCleanExpiredFiles(t *testing.T) {
 	testName := "Test"
 	expireTime := 30 * time.Minute
 	now := time.Now()
-	expired := now.Add(-expireTime)
+	expired := now.Add(-expireTime - time.Minute)
 	filesExpired := [testFileCount]string{}
 	filesNonExpired := [testFileCount]string{}
 
-	// 创建过期和未过期的文件
+	// 创建过期和未过期的文件 (按修改时间判断是否过期)
 	for i := 0; i < testFileCount; i++ {
 		num := ""
 		if i > 0 {
 			num = fmt.Sprintf("(%d)", i)
 		}
-		fileExpired := testName + "_" + expired.Format("060102150405") + "_" + strconv.Itoa(os.Getpid()) + num + ".txt"
-		fileExpired = path.Join(testDir, fileExpired)
+		fileExpired := testName + "_" + expired.Format("060102150405") + "_" + strconv.Itoa(os.Getpid()) + num + ".log"
+		fileExpired = filepath.Join(testDir, fileExpired)
 		os.WriteFile(fileExpired, nil, 0644)
+		os.Chtimes(fileExpired, expired, expired)
 		filesExpired[i] = fileExpired
 		t.Logf("Created expired file: %s", filepath.Base(fileExpired))
 	}
@@ -50,31 +61,1767 @@ func TestCleanExpiredFiles(t *testing.T) {
 		if i > 0 {
 			num = fmt.Sprintf("(%d)", i)
 		}
-		fileNonExpired := testName + "_" + now.Format("060102150405") + "_" + strconv.Itoa(os.Getpid()) + num + ".txt"
-		fileNonExpired = path.Join(testDir, fileNonExpired)
+		fileNonExpired := testName + "_" + now.Format("060102150405") + "_" + strconv.Itoa(os.Getpid()) + num + ".log"
+		fileNonExpired = filepath.Join(testDir, fileNonExpired)
 		os.WriteFile(fileNonExpired, nil, 0644)
 		filesNonExpired[i] = fileNonExpired
 		t.Logf("Created non-expired file: %s", filepath.Base(fileNonExpired))
 	}
+	// 当前日志文件即使过期也不删除
+	current := filepath.Join(testDir, testName+".log")
+	os.WriteFile(current, nil, 0644)
+	os.Chtimes(current, expired, expired)
+	other := filepath.Join(testDir, "Other.log")
+	os.WriteFile(other, nil, 0644)
+	os.Chtimes(other, expired, expired)
+	// 以日志文件名为前缀的其他日志器的文件不删除
+	prefixed := []string{filepath.Join(testDir, testName+"s.log"), filepath.Join(testDir, testName+"_audit.log")}
+	for _, file := range prefixed {
+		os.WriteFile(file, nil, 0644)
+		os.Chtimes(file, expired, expired)
+	}
 
 	// 清理过期文件
-	cleanExpireFiles(testDir, testName, expireTime)
+	cleanExpireFiles(testDir, testName, current, expireTime)
 
 	// 检查过期的文件是否已经删除，未过期的文件是否仍然存在
 	for _, file := range filesExpired {
-		_, err := os.Stat(file)
-		if os.IsExist(err) {
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
 			t.Errorf("Expired file not cleaned up: %s", filepath.Base(file))
 		} else {
 			t.Logf("Cleaned up expired file: %s", filepath.Base(file))
 		}
 	}
-	for _, file := range filesNonExpired {
-		_, err := os.Stat(file)
-		if os.IsNotExist(err) {
-			t.Errorf("Non-expired file not kept: %s", filepath.Base(file))
+	for _, file := range append(append(filesNonExpired[:], current, other), prefixed...) {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("File not kept: %s", filepath.Base(file))
 		} else {
-			t.Logf("Kept non-expired file: %s", filepath.Base(file))
+			t.Logf("Kept file: %s", filepath.Base(file))
+		}
+	}
+}
+
+func TestExecutorClose(t *testing.T) {
+	// 关闭时仍有任务提交, 每个任务都执行且关闭后提交的任务在调用协程中执行
+	e := newExecutor(defaultExecutor{})
+	var count atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				e.Go(TaskCallback, func() { count.Add(1) })
+			}
+		}()
+	}
+	e.Close()
+	wg.Wait()
+	if n := count.Load(); n != 800 {
+		t.Errorf("Executed %d tasks, want 800", n)
+	}
+}
+
+func TestFlusherRepeat(t *testing.T) {
+	var count atomic.Int32
+	f := newFlusher(newExecutor(defaultExecutor{}), 20*time.Millisecond, 0, func() { count.Add(1) })
+	f.Start()
+	time.Sleep(110 * time.Millisecond)
+	f.Close()
+
+	if n := count.Load(); n < 3 {
+		t.Errorf("Flusher flushed %d times, want at least 3", n)
+	}
+}
+
+func TestFlusherLatency(t *testing.T) {
+	flushed := make(chan struct{}, 1)
+	f := newFlusher(newExecutor(defaultExecutor{}), time.Hour, 20*time.Millisecond, func() { flushed <- struct{}{} })
+	f.Start()
+	defer f.Close()
+
+	f.Dirty()
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		t.Fatal("Buffered data not flushed within max latency")
+	}
+
+	// 缓冲区已被刷新, 不应再触发
+	f.Dirty()
+	time.Sleep(5 * time.Millisecond)
+	f.Reset()
+	select {
+	case <-flushed:
+		t.Error("Flusher flushed after reset")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// 刷新后写入的数据, 两个通知同时待处理时仍需计时
+	for i := 0; i < 20; i++ {
+		f := newFlusher(newExecutor(defaultExecutor{}), time.Hour, time.Millisecond, func() { flushed <- struct{}{} })
+		f.Reset()
+		f.Dirty()
+		f.Start()
+		select {
+		case <-flushed:
+		case <-time.After(time.Second):
+			t.Fatal("Data written after reset not flushed")
+		}
+		f.Close()
+	}
+}
+
+func TestRingConcurrent(t *testing.T) {
+	const (
+		producers = 8
+		number    = 10000
+	)
+
+	r := newRing(64)
+	for p := 0; p < producers; p++ {
+		go func(p int) {
+			for n := 0; n < number; n++ {
+				m := &groMsg{level: p}
+				for !r.Push(m) {
+					runtime.Gosched()
+				}
+			}
+		}(p)
+	}
+
+	received := [producers]int{}
+	for total := 0; total < producers*number; {
+		m, ok := r.Pop()
+		if !ok {
+			runtime.Gosched()
+			continue
+		}
+		received[m.level]++
+		total++
+	}
+	for p, n := range received {
+		if n != number {
+			t.Errorf("Producer %d: received %d messages, want %d", p, n, number)
+		}
+	}
+	if _, ok := r.Pop(); ok {
+		t.Error("Ring not empty after all messages received")
+	}
+
+	// 最小容量的队列不覆盖未读取的消息
+	r = newRing(1)
+	a, b := &groMsg{level: 1}, &groMsg{level: 2}
+	if !r.Push(a) || !r.Push(b) || r.Push(a) {
+		t.Fatal("Unexpected ring capacity")
+	}
+	if m, ok := r.Pop(); !ok || m != a {
+		t.Errorf("Popped %v, want the first message", m)
+	}
+	if m, ok := r.Pop(); !ok || m != b {
+		t.Errorf("Popped %v, want the second message", m)
+	}
+}
+
+// 测试执行器 (记录任务与生命周期)
+type testExecutor struct {
+	lock    sync.Mutex
+	tasks   map[string]int
+	started bool
+	stopped bool
+}
+
+func (e *testExecutor) Go(task string, f func()) {
+	e.lock.Lock()
+	e.tasks[task]++
+	e.lock.Unlock()
+	go f()
+}
+
+func (e *testExecutor) OnStart() { e.started = true }
+
+func (e *testExecutor) OnStop() { e.stopped = true }
+
+func TestExecutorTasks(t *testing.T) {
+	exec := &testExecutor{tasks: map[string]int{}}
+	logger := New(nil,
+		WithLevel(LevelVerBose),
+		WithEnableAsyn(true),
+		WithDisableSave(true),
+		WithDisablePrint(true),
+		WithExecutor(exec),
+		WithMsgCallback(func(int, string) {}),
+	)
+	logger.Trace("Hello World!\n")
+	logger.Close()
+
+	if !exec.started || !exec.stopped {
+		t.Errorf("Lifecycle hooks not called: started=%v, stopped=%v", exec.started, exec.stopped)
+	}
+	for _, task := range []string{TaskConsumer, TaskFlusher, TaskCallback} {
+		if exec.tasks[task] == 0 {
+			t.Errorf("Task %s not run through executor", task)
+		}
+	}
+}
+
+// 创建控制台输出重定向到临时文件的日志器, 返回读取输出的函数
+func newCaptureLogger(t *testing.T, opts ...Option) (*Logger, func() string) {
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { out.Close() })
+
+	stdout := os.Stdout
+	os.Stdout = out
+	logger := New(nil, append([]Option{WithDisableSave(true)}, opts...)...)
+	os.Stdout = stdout
+
+	return logger, func() string {
+		text, err := os.ReadFile(out.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(text)
+	}
+}
+
+func TestRecoverRepanic(t *testing.T) {
+	logger, output := newCaptureLogger(t,
+		WithEnableAsyn(true),
+		WithFatalAction(FatalPanic),
+	)
+	defer logger.Close()
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("Recovered %v, want repanic with boom", r)
+			}
+		}()
+		defer logger.Recover()
+		panic("boom")
+	}()
+
+	// 重新抛出前已写入
+	if text := output(); !strings.Contains(text, "panic: boom\n") || !strings.Contains(text, "runtime/debug.Stack") {
+		t.Errorf("Unexpected fatal record: %q", text)
+	}
+}
+
+func TestFatalAction(t *testing.T) {
+	for _, asyn := range []bool{false, true} {
+		logger, output := newCaptureLogger(t,
+			WithEnableAsyn(asyn),
+			WithFatalAction(FatalPanic),
+		)
+
+		func() {
+			defer func() {
+				if r := recover(); r != "fatal 1" {
+					t.Errorf("Fatal panicked with %v, want fatal 1", r)
+				}
+			}()
+			logger.Fatalln("fatal", 1)
+		}()
+		// 抛出异常前已写入
+		if text := output(); !strings.Contains(text, "FATAL") || !strings.Contains(text, "fatal 1\n") {
+			t.Errorf("Fatal record not written before action: %q", text)
+		}
+
+		func() {
+			defer func() {
+				if r := recover(); r != "panic 2" {
+					t.Errorf("Panic panicked with %v, want panic 2", r)
+				}
+			}()
+			logger.Panicf("panic %d\n", 2)
+		}()
+		if text := output(); !strings.Contains(text, "PANIC") || !strings.Contains(text, "panic 2\n") {
+			t.Errorf("Panic record not written before panic: %q", text)
+		}
+		logger.Close()
+	}
+
+	logger, output := newCaptureLogger(t, WithFatalAction(FatalContinue))
+	logger.Fatal("continue\n")
+	logger.Close()
+	if text := output(); !strings.Contains(text, "continue\n") {
+		t.Errorf("Fatal record not written: %q", text)
+	}
+
+	// 退出码为0或无效时使用默认值, 以0退出时使用 ExitSuccess
+	for code, want := range map[int]int{0: defaultExitCode, 3: 3, -2: defaultExitCode, ExitSuccess: 0} {
+		logger, _ := newCaptureLogger(t, WithExitCode(code))
+		logger.Close()
+		if got := logger.config.exitCode(); got != want {
+			t.Errorf("Exit code %d = %d, want %d", code, got, want)
+		}
+	}
+	// 未设置退出码的配置 (如配置字面量或从JSON加载) 使用默认值
+	cfg := DefaultConfig()
+	cfg.ExitCode = 0
+	logger = New(cfg, WithDisableSave(true), WithDisablePrint(true))
+	logger.Close()
+	if got := logger.config.exitCode(); got != defaultExitCode {
+		t.Errorf("Exit code = %d, want %d", got, defaultExitCode)
+	}
+}
+
+func TestHookRecord(t *testing.T) {
+	var records []Record
+	logger := New(nil,
+		WithLevel(LevelVerBose),
+		WithDisableSave(true),
+		WithDisablePrint(true),
+		WithHook(HookFunc(func(r Record) error {
+			records = append(records, r)
+			return nil
+		}), LevelWarning, LevelError),
+	)
+	defer logger.Close()
+
+	logger.Trace("ignored\n")
+	logger.With(Any("id", 7), Any("name", "a b")).Warningln("hello", "world")
+	_, file, line, _ := runtime.Caller(0)
+
+	if len(records) != 1 {
+		t.Fatalf("Hook fired %d times, want 1", len(records))
+	}
+	r := records[0]
+	if r.Level != LevelWarning || r.Message != "hello world" || r.Time.IsZero() {
+		t.Errorf("Unexpected record: %+v", r)
+	}
+	if r.File != file || r.Line != line-1 || !strings.HasSuffix(r.Function, ".TestHookRecord") {
+		t.Errorf("Unexpected caller: %s:%d %s", r.File, r.Line, r.Function)
+	}
+	if len(r.Fields) != 2 || r.Fields[0] != Any("id", 7) || r.Fields[1] != Any("name", "a b") {
+		t.Errorf("Unexpected fields: %v", r.Fields)
+	}
+}
+
+func TestAsynHookLogging(t *testing.T) {
+	var logger *Logger
+	var mutex sync.Mutex
+	var messages []string
+	hooked := make(chan struct{})
+	logger = New(nil,
+		WithStyle(StyleBasic),
+		WithDisableSave(true),
+		WithDisablePrint(true),
+		WithEnableAsyn(true),
+		WithAsynMaxBuffer(1),
+		WithFatalAction(FatalContinue),
+		WithHook(HookFunc(func(r Record) error {
+			mutex.Lock()
+			messages = append(messages, r.Message)
+			mutex.Unlock()
+			if r.Message == "start" { // 在钩子中记录致命日志、刷新并超出消息队列容量
+				logger.Fatalln("fatal")
+				logger.Flush()
+				for i := 0; i < 4; i++ {
+					logger.Warningln("hooked", i)
+				}
+				close(hooked)
+			}
+			return nil
+		})),
+	)
+
+	done := make(chan struct{})
+	go func() {
+		logger.Errorln("start")
+		for i := 0; i < 4; i++ {
+			logger.Errorln("record", i)
+		}
+		<-hooked // 关闭后记录的日志被丢弃
+		logger.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Logging from a sync hook deadlocked")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(messages) != 10 || messages[0] != "start" {
+		t.Fatalf("Unexpected hooked messages: %q", messages)
+	}
+	for i, want := 1, 0; i < len(messages) && want < 4; i++ { // 外部记录的日志保持顺序
+		if strings.HasPrefix(messages[i], "record ") {
+			if messages[i] != fmt.Sprintf("record %d", want) {
+				t.Fatalf("Unexpected hook order: %q", messages)
+			}
+			want++
+		}
+	}
+}
+
+func TestStorageWrite(t *testing.T) {
+	testDir := t.TempDir()
+	for i := 0; i < 2; i++ { // 第二次以追加方式打开已存在的文件
+		logger := New(nil,
+			WithStyle(StyleBasic),
+			WithDisablePrint(true),
+			WithFileDir(testDir),
+			WithFileName("Test"),
+		)
+		logger.Errorf("line %d\n", i)
+		logger.Close()
+		if err := logger.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	text, err := os.ReadFile(filepath.Join(testDir, "Test.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "line 0\nline 1\n" {
+		t.Errorf("Unexpected log file content: %q", text)
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	// 以普通文件作为目录, 创建日志文件失败
+	testFile := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(testFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var errs []error
+	logger := New(nil,
+		WithDisablePrint(true),
+		WithFileDir(filepath.Join(testFile, "log")),
+		WithErrorHandler(func(err error) { errs = append(errs, err) }),
+	)
+	logger.Error("lost\n")
+	logger.Close()
+
+	if len(errs) == 0 || logger.Err() == nil {
+		t.Fatalf("Storage error not reported: %v, %v", errs, logger.Err())
+	}
+	if logger.Err() != errs[len(errs)-1] {
+		t.Errorf("Err() = %v, want last reported error %v", logger.Err(), errs[len(errs)-1])
+	}
+}
+
+func TestErrorHandlerLogging(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("/dev/full not available")
+	}
+	testDir := t.TempDir()
+	if err := os.Symlink("/dev/full", filepath.Join(testDir, "Test.log")); err != nil {
+		t.Skip(err)
+	}
+
+	for _, asyn := range []bool{false, true} {
+		var logger *Logger
+		var reported atomic.Int32
+		logger = New(nil,
+			WithStyle(StyleBasic),
+			WithDisablePrint(true),
+			WithEnableAsyn(asyn),
+			WithFileDir(testDir),
+			WithFileName("Test"),
+			WithWriteBufferSize(0),
+			WithErrorHandler(func(err error) {
+				reported.Add(1)
+				logger.Warningln("log file failed:", err) // 在错误处理函数中记录日志
+			}),
+		)
+
+		done := make(chan struct{})
+		go func() {
+			logger.Errorln("full")
+			logger.Close()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Asyn %v: logging from the error handler deadlocked", asyn)
+		}
+		if reported.Load() == 0 {
+			t.Errorf("Asyn %v: write error not reported", asyn)
+		}
+	}
+
+	// 异步模式下钩子错误的处理函数记录日志, 消息队列已满
+	var logger *Logger
+	var reported atomic.Int32
+	logger = New(nil,
+		WithStyle(StyleBasic),
+		WithDisableSave(true),
+		WithDisablePrint(true),
+		WithEnableAsyn(true),
+		WithAsynMaxBuffer(1),
+		WithHook(HookFunc(func(r Record) error { return errors.New("hook failed") })),
+		WithErrorHandler(func(err error) {
+			if reported.Add(1) < 100 {
+				for i := 0; i < 4; i++ { // 超出消息队列容量
+					logger.Warningln("reported:", err)
+				}
+			}
+		}),
+	)
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			logger.Errorln("record", i)
+		}
+		logger.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Logging from the hook error handler deadlocked")
+	}
+	if reported.Load() == 0 {
+		t.Error("Hook error not reported")
+	}
+}
+
+func TestFallbackRetry(t *testing.T) {
+	// 以普通文件作为目录, 日志文件暂时不可用
+	testDir := filepath.Join(t.TempDir(), "dir")
+	if err := os.WriteFile(testDir, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := New(nil,
+		WithStyle(StyleBasic),
+		WithDisablePrint(true),
+		WithFileDir(testDir),
+		WithFileName("Test"),
+		WithRetryInterval("10ms"),
+		WithFallback(FallbackMemory),
+		WithErrorHandler(func(error) {}),
+	)
+	logger.Error("fallback\n")
+	if text := string(logger.FallbackLogs()); text != "fallback\n" {
+		t.Errorf("Unexpected fallback logs: %q", text)
+	}
+
+	// 恢复后重新打开日志文件
+	os.Remove(testDir)
+	time.Sleep(20 * time.Millisecond)
+	logger.Error("primary\n")
+	logger.Close()
+
+	text, err := os.ReadFile(filepath.Join(testDir, "Test.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "primary\n" {
+		t.Errorf("Unexpected log file content: %q", text)
+	}
+	if text := string(logger.FallbackLogs()); text != "fallback\n" {
+		t.Errorf("Unexpected fallback logs after recovery: %q", text)
+	}
+}
+
+func TestFallbackPartialWrite(t *testing.T) {
+	// 写入中途失败时, 只将未写入日志文件的部分写入备用输出
+	testDir := t.TempDir()
+	logger := New(nil,
+		WithStyle(StyleBasic),
+		WithDisablePrint(true),
+		WithFileDir(testDir),
+		WithFileName("Test"),
+		WithWriteBufferSize(0),
+		WithRetryInterval("1h"),
+		WithFallback(FallbackMemory),
+		WithErrorHandler(func(error) {}),
+	)
+	s := logger.handler.(*groHandlerSync).pusher.storage
+	s.count.w = &limitWriter{w: s.count.w, limit: 4}
+	logger.Error("0123456789\n")
+	logger.Close()
+
+	text, err := os.ReadFile(filepath.Join(testDir, "Test.log"))
+	if err != nil || string(text) != "0123" {
+		t.Errorf("Unexpected log file content %q: %v", text, err)
+	}
+	if text := string(logger.FallbackLogs()); text != "456789\n" {
+		t.Errorf("Unexpected fallback logs: %q", text)
+	}
+}
+
+// 写入指定大小后失败的写入器
+type limitWriter struct {
+	w     io.Writer
+	limit int
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if len(p) <= l.limit {
+		l.limit -= len(p)
+		return l.w.Write(p)
+	}
+	n, _ := l.w.Write(p[:l.limit])
+	l.limit -= n
+	return n, io.ErrShortWrite
+}
+
+func TestFallbackMemoryLimit(t *testing.T) {
+	m := newMemory(10)
+	m.Write([]byte("aaaa\n"))
+	m.Write([]byte("bbbb\n"))
+	m.Write([]byte("cccc\n"))
+	if text := string(m.Bytes()); text != "bbbb\ncccc\n" {
+		t.Errorf("Unexpected memory content: %q", text)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	var records []Record
+	logger, output := newCaptureLogger(t,
+		WithStyle(StyleBasic),
+		WithRedact(RedactAll),
+		WithRedactPatterns(`order-\d+`),
+		WithRedactFields("Session"),
+		WithRedactMask("***"),
+		WithHook(HookFunc(func(r Record) error {
+			records = append(records, r)
+			return nil
+		})),
+	)
+	child := logger.With(Any("session", "abc"), Any("contact", "bob@example.com"), Any("id", 7))
+	child.Errorln("login", "api_key=s3cr3t", "Authorization: Bearer eyJhbGciOi.x-y", "card 4111 1111 1111 1111", "order-42")
+	child.Errorln("not a card 1234 5678 9012 3456")
+	child.Errorln("failed:", fmt.Errorf("connect: %w", errors.New("password=hunter2")))
+	logger.Close()
+
+	want := "login api_key=*** Authorization: Bearer *** card *** *** session=*** contact=*** id=7\n" +
+		"not a card 1234 5678 9012 3456 session=*** contact=*** id=7\n" +
+		"failed: connect: password=*** session=*** contact=*** id=7\n"
+	if text := output(); text != want {
+		t.Errorf("Unexpected output:\n%q\nwant:\n%q", text, want)
+	}
+	if len(records) != 3 || strings.Contains(records[0].Message, "s3cr3t") || records[0].Fields[0].Value != "***" {
+		t.Fatalf("Hook received unredacted record: %+v", records)
+	}
+	// 钩子获取的错误已脱敏, 且无法通过错误链获取原文
+	if err := records[2].Error; err == nil || err.Error() != "connect: password=***" || errors.Unwrap(err) != nil {
+		t.Errorf("Hook received unredacted error: %v", err)
+	}
+	// 日志器的附加字段未被修改
+	if child.fields[0].Value != "abc" {
+		t.Errorf("Logger fields modified: %v", child.fields)
+	}
+
+	// 致命错误处理和抛出的异常使用脱敏后的消息
+	var fatal any
+	logger, _ = newCaptureLogger(t,
+		WithRedact(RedactAPIKey),
+		WithFatalHandling(func(l *Logger, v any) { fatal = v }),
+	)
+	defer logger.Close()
+	logger.Fatalw("token=abc123")
+	if fatal != "token=[REDACTED]" {
+		t.Errorf("Fatal handled with %v", fatal)
+	}
+	for _, panics := range []func(){
+		func() { logger.Panicln("token=abc123") },
+		func() { logger.Caller(0).Panicf("token=%s", "abc123") },
+		func() { logger.PanicCtx(context.Background(), "token=abc123") },
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != "token=[REDACTED]" {
+					t.Errorf("Panicked with %v", r)
+				}
+			}()
+			panics()
+		}()
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		escape int
+		want   string
+	}{
+		{EscapeNone, "user admin\nTRACE forged \x1b[31mred\x1b[0m\n"},
+		{EscapeControl, "user admin\n\tTRACE forged \\x1b[31mred\\x1b[0m\n"},
+		{EscapeAll, "user admin\\nTRACE forged \\x1b[31mred\\x1b[0m\n"},
+	}
+	for _, test := range tests {
+		logger, output := newCaptureLogger(t, WithStyle(StyleBasic), WithPrintEscape(test.escape))
+		logger.Errorln("user admin\nTRACE forged \x1b[31mred\x1b[0m")
+		logger.Close()
+		if text := output(); text != test.want {
+			t.Errorf("Escape %d: unexpected output %q, want %q", test.escape, text, test.want)
+		}
+	}
+
+	// 字段名称和值始终转义
+	logger, output := newCaptureLogger(t, WithStyle(StyleBasic))
+	logger.With(Any("user", "a\x1b[2Jb"), Any("bad", "\xff")).Errorln("login")
+	logger.With(Any("k", struct{ S string }{"a\nFAKE RECORD"}), Any("ke\ny", 1), Int("a b", 2)).Named("x\ny").Errorln("forged")
+	logger.Close()
+	want := "login user=\"a\\x1b[2Jb\" bad=\"\\xff\"\n" +
+		"[x\\ny] forged k=\"{a\\nFAKE RECORD}\" \"ke\\ny\"=1 \"a b\"=2\n"
+	if text := output(); text != want {
+		t.Errorf("Unexpected output %q, want %q", text, want)
+	}
+}
+
+func TestChain(t *testing.T) {
+	testDir := t.TempDir()
+	key := []byte("secret")
+	for i := 0; i < 2; i++ { // 第二次以追加方式打开已存在的文件, 从文件末尾恢复校验值
+		logger := New(nil,
+			WithStyle(StyleBasic),
+			WithDisablePrint(true),
+			WithFileDir(testDir),
+			WithFileName("Test"),
+			WithMaxFileSize(300),
+			WithEnableChain(true),
+			WithChainKey(key),
+		)
+		for j := 0; j < 1+i*2; j++ { // 第二次写入时切换日志文件
+			logger.Errorf("session %d record %d\nsecond line\n", i, j)
+		}
+		logger.Close()
+		if err := logger.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := []string{filepath.Join(testDir, "Test.log"), filepath.Join(testDir, "Test(1).log")}
+	if err := VerifyChain(key, files...); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChain([]byte("other"), files...); err == nil {
+		t.Error("Verify with wrong key succeeded")
+	}
+	// 日志文件顺序错误
+	if err := VerifyChain(key, files[1], files[0]); err == nil {
+		t.Error("Verify with wrong file order succeeded")
+	}
+
+	text, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(text), "\n")
+	tests := []struct {
+		name string
+		text string
+		line int
+	}{
+		{"modify", strings.Join(lines[:1], "") + strings.Replace(lines[1], "record", "rec0rd", 1) + strings.Join(lines[2:], ""), 2},
+		{"delete", strings.Join(lines[:1], "") + strings.Join(lines[3:], ""), 2},
+		{"truncate", strings.Join(lines[:4], ""), 4},
+	}
+	for _, test := range tests {
+		if err := os.WriteFile(files[0], []byte(test.text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		var chainErr *ChainError
+		if err := VerifyChain(key, files...); !errors.As(err, &chainErr) || chainErr.File != files[0] || chainErr.Line != test.line {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+	}
+}
+
+func TestChainForged(t *testing.T) {
+	testDir := t.TempDir()
+	name := filepath.Join(testDir, "Test.log")
+	open := func() *Logger {
+		return New(nil,
+			WithStyle(StyleBasic),
+			WithDisablePrint(true),
+			WithFileDir(testDir),
+			WithFileName("Test"),
+			WithSaveEscape(EscapeNone),
+			WithEnableChain(true),
+		)
+	}
+
+	// 消息中的行尾校验值格式和首行的链头格式被转义
+	logger := open()
+	logger.Errorln("forged" + chainSuffix + strings.Repeat("0", chainHexSize) + "\nnext")
+	logger.Errorln(chainHeader + strings.Repeat("a", chainHexSize) + "\nnext")
+	logger.Errorln("first\n" + chainHeader + strings.Repeat("a", chainHexSize) + "\nnext")
+	logger.Close()
+	if err := VerifyChain(nil, name); err != nil {
+		t.Fatal(err)
+	}
+
+	// 进程崩溃留下不完整的最后一行, 链头从新行开始
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("partial")
+	file.Close()
+	logger = open()
+	logger.Errorln("after")
+	logger.Close()
+
+	text, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(text), "partial\n"+chainHeader) {
+		t.Errorf("Chain header not on a new line: %q", text)
+	}
+	var chainErr *ChainError
+	if err := VerifyChain(nil, name); !errors.As(err, &chainErr) || chainErr.Line != 9 || chainErr.Reason != "incomplete record" {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestChainRestart(t *testing.T) {
+	testDir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < 3; i++ { // 每次启动创建新的日志文件, 从最新的日志文件末尾恢复校验值
+		cfg := DefaultConfig()
+		cfg.startTime = start.Add(time.Duration(i) * time.Minute)
+		logger := New(cfg,
+			WithStyle(StyleBasic),
+			WithDisablePrint(true),
+			WithFileDir(testDir),
+			WithFileName("Test"),
+			WithEnableFileTime(true),
+			WithEnableChain(true),
+		)
+		logger.Errorf("run %d\n", i)
+		logger.Close()
+	}
+
+	files, err := ListLogFiles(testDir, "Test")
+	if err != nil || len(files) != 3 {
+		t.Fatalf("Unexpected log files %v, %v", files, err)
+	}
+	if err := VerifyChain(nil, files...); err != nil {
+		t.Fatal(err)
+	}
+	// 最早的日志文件被删除
+	if err := VerifyChain(nil, files[1:]...); err == nil {
+		t.Error("Verify without the oldest file succeeded")
+	}
+	// 从已知的校验值开始校验
+	text, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := strings.TrimSuffix(string(text), "\n")
+	prev := line[strings.LastIndex(line, chainSuffix)+len(chainSuffix):]
+	if err := VerifyChainFrom(nil, prev, files[1:]...); err != nil {
+		t.Error(err)
+	}
+	if err := VerifyChainFrom(nil, prev, files[2:]...); err == nil {
+		t.Error("Verify from a stale chain value succeeded")
+	}
+}
+
+func TestChainPrefixName(t *testing.T) {
+	// 两个日志器共用目录, 其中一个的文件名是另一个的前缀, 各自从自己最新的日志文件恢复校验值
+	testDir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	for i, name := range []string{"Test", "Tests", "Test", "Tests"} {
+		cfg := DefaultConfig()
+		cfg.startTime = start.Add(time.Duration(i) * time.Minute)
+		logger := New(cfg,
+			WithStyle(StyleBasic),
+			WithDisablePrint(true),
+			WithFileDir(testDir),
+			WithFileName(name),
+			WithEnableFileTime(true),
+			WithEnableChain(true),
+		)
+		logger.Errorf("run %d\n", i)
+		logger.Close()
+		if err := logger.Err(); err != nil {
+			t.Fatal(err)
+		}
+		mtime := cfg.startTime.Truncate(time.Second)
+		os.Chtimes(filepath.Join(testDir, fmt.Sprintf("%s_%s_%d.log", name, cfg.startTime.Format("060102150405"), os.Getpid())), mtime, mtime)
+	}
+
+	for _, name := range []string{"Test", "Tests"} {
+		files, err := ListLogFiles(testDir, name)
+		if err != nil || len(files) != 2 {
+			t.Fatalf("Unexpected %s log files %v, %v", name, files, err)
+		}
+		if err := VerifyChain(nil, files...); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestListLogFiles(t *testing.T) {
+	testDir := t.TempDir()
+	names := []string{"Test(10).log", "Test(2).log", "Test.log", "Test(1).log", "Other.log", "Tests.log", "Test_audit.log"}
+	mtime := time.Now().Truncate(time.Second)
+	for _, name := range names {
+		path := filepath.Join(testDir, name)
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := ListLogFiles(testDir, "Test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 { // 不包含以日志文件名为前缀的其他日志器的文件
+		t.Fatalf("Unexpected log files %v", files)
+	}
+	for i, name := range []string{"Test.log", "Test(1).log", "Test(2).log", "Test(10).log"} {
+		if i >= len(files) || filepath.Base(files[i]) != name {
+			t.Fatalf("Unexpected order %v", files)
+		}
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	testDir := t.TempDir()
+	name := filepath.Join(testDir, "Test.log")
+	key := bytes.Repeat([]byte{7}, 32)
+	write := func(text string) {
+		logger := New(nil,
+			WithStyle(StyleBasic),
+			WithDisablePrint(true),
+			WithFileDir(testDir),
+			WithFileName("Test"),
+			WithEnableChain(true),
+			WithEncryptKey(key),
+		)
+		logger.Errorln(text)
+		logger.Close()
+		if err := logger.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	decrypt := func(key []byte) (string, error) {
+		file, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		r, err := NewDecryptReader(file, key)
+		if err != nil {
+			return "", err
+		}
+		text, err := io.ReadAll(r)
+		return string(text), err
+	}
+
+	write("secret 0")
+	write("secret 1") // 以追加方式打开, 写入新的数据块
+	raw, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("secret")) {
+		t.Fatalf("Log file is not encrypted: %q", raw)
+	}
+	text, err := decrypt(key)
+	if err != nil || !strings.Contains(text, "secret 0 ~") || !strings.Contains(text, "secret 1 ~") {
+		t.Fatalf("Unexpected decrypted content %q: %v", text, err)
+	}
+	if _, err := decrypt(bytes.Repeat([]byte{8}, 32)); err == nil {
+		t.Error("Decrypt with wrong key succeeded")
+	}
+
+	// 最后的数据块不完整时只解密完整的数据块, 追加时截断不完整的数据块
+	if err := os.WriteFile(name, raw[:len(raw)-5], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if text, err := decrypt(key); err != nil || strings.Contains(text, "secret 1") || !strings.Contains(text, "secret 0") {
+		t.Errorf("Unexpected partial content %q: %v", text, err)
+	}
+	write("secret 2")
+	text, err = decrypt(key)
+	if err != nil || strings.Count(text, "\n") != 3 || !strings.Contains(text, "secret 2 ~") {
+		t.Fatalf("Unexpected content after append %q: %v", text, err)
+	}
+	plain := filepath.Join(testDir, "plain.txt")
+	if err := os.WriteFile(plain, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChain(nil, plain); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncryptPlainFile(t *testing.T) {
+	// 启用加密前的日志文件保留不变, 切换到下一个日志文件并继续链式校验
+	testDir := t.TempDir()
+	key := bytes.Repeat([]byte{7}, 32)
+	for _, key := range [][]byte{nil, key} {
+		logger := New(nil,
+			WithStyle(StyleBasic),
+			WithDisablePrint(true),
+			WithFileDir(testDir),
+			WithFileName("Test"),
+			WithEnableChain(true),
+			WithEncryptKey(key),
+		)
+		logger.Errorln("record", len(key))
+		logger.Close()
+		if err := logger.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plain := filepath.Join(testDir, "Test.log")
+	raw, err := os.ReadFile(plain)
+	if err != nil || !strings.Contains(string(raw), "record 0 ~") || strings.Contains(string(raw), "record 32") {
+		t.Fatalf("Plain log file changed %q: %v", raw, err)
+	}
+	file, err := os.Open(filepath.Join(testDir, "Test(1).log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	r, err := NewDecryptReader(file, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := io.ReadAll(r)
+	if err != nil || !strings.Contains(string(text), "record 32 ~") {
+		t.Fatalf("Unexpected decrypted content %q: %v", text, err)
+	}
+	decrypted := filepath.Join(t.TempDir(), "Test(1).log")
+	if err := os.WriteFile(decrypted, text, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChain(nil, plain, decrypted); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncryptChunks(t *testing.T) {
+	aead, err := newAEAD(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypt := func(count int) ([]byte, [][]byte) {
+		var buf bytes.Buffer
+		state, err := writeEncryptHeader(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := newEncrypter(aead, &buf, state)
+		for i := 0; i < count; i++ {
+			fmt.Fprintf(e, "record %d\n", i)
+		}
+		// 按块长度拆分数据块
+		raw, chunks := buf.Bytes(), [][]byte(nil)
+		for b := raw[encryptHeadSize:]; len(b) > 0; {
+			n := 4 + int(binary.BigEndian.Uint32(b)) + 4
+			chunks, b = append(chunks, b[:n]), b[n:]
+		}
+		return raw[:encryptHeadSize], chunks
+	}
+	decrypt := func(head []byte, chunks ...[]byte) (string, error) {
+		r, err := newDecrypter(bytes.NewReader(bytes.Join(append([][]byte{head}, chunks...), nil)), aead)
+		if err != nil {
+			return "", err
+		}
+		text, err := io.ReadAll(r)
+		return string(text), err
+	}
+
+	head, chunks := encrypt(3)
+	if text, err := decrypt(head, chunks...); err != nil || text != "record 0\nrecord 1\nrecord 2\n" {
+		t.Fatalf("Unexpected content %q: %v", text, err)
+	}
+	other, _ := encrypt(3)
+	for name, test := range map[string][][]byte{
+		"dropped":   {chunks[0], chunks[2]},
+		"reordered": {chunks[1], chunks[0], chunks[2]},
+	} {
+		if _, err := decrypt(head, test...); err == nil {
+			t.Errorf("Decrypt %s chunks succeeded", name)
+		}
+	}
+	if _, err := decrypt(other, chunks...); err == nil {
+		t.Error("Decrypt chunks of another file succeeded")
+	}
+
+	// 从文件末尾读取时只读取最后的数据块
+	head, chunks = encrypt(1000)
+	raw := bytes.Join(append([][]byte{head}, chunks...), nil)
+	counter := &countReaderAt{r: bytes.NewReader(raw)}
+	tail, end, state, err := readEncryptedTail(counter, int64(len(raw)), aead, chainTailSize)
+	if err != nil || !bytes.HasSuffix(tail, []byte("record 999\n")) || end != int64(len(raw)) || state.index != 1000 {
+		t.Fatalf("Unexpected tail %q, end %d, index %d: %v", tail, end, state.index, err)
+	}
+	if counter.n > int64(len(raw))/10 {
+		t.Errorf("Read %d of %d bytes for the tail", counter.n, len(raw))
+	}
+	// 最后的数据块不完整时从文件开始解密
+	tail, end, state, err = readEncryptedTail(bytes.NewReader(raw[:len(raw)-5]), int64(len(raw)-5), aead, chainTailSize)
+	if err != nil || !bytes.HasSuffix(tail, []byte("record 998\n")) || end != int64(len(raw)-len(chunks[999])) || state.index != 999 {
+		t.Fatalf("Unexpected partial tail %q, end %d, index %d: %v", tail, end, state.index, err)
+	}
+}
+
+// 统计读取字节数的读取器
+type countReaderAt struct {
+	r io.ReaderAt
+	n int64
+}
+
+func (c *countReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.n += int64(n)
+	return n, err
+}
+
+func TestColor(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   map[string]string
+		opts  []Option
+		color string
+	}{
+		{"auto file", nil, nil, ""},
+		{"auto force", map[string]string{"FORCE_COLOR": "1"}, nil, levelStyleStarts[LevelError]},
+		{"auto no color", map[string]string{"NO_COLOR": "1"}, []Option{WithColor(ColorAuto)}, ""},
+		{"always", map[string]string{"NO_COLOR": "1"}, []Option{WithColor(ColorAlways)}, levelStyleStarts[LevelError]},
+		{"never", map[string]string{"FORCE_COLOR": "1"}, []Option{WithColor(ColorNever)}, ""},
+		{"palette", nil, []Option{WithColor(ColorAlways), WithColorPalette(map[int]string{LevelError: "1;91"})}, "\x1b[1;91m"},
+		{"palette disabled", nil, []Option{WithColor(ColorAlways), WithColorPalette(map[int]string{LevelError: ""})}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("FORCE_COLOR", "")
+			os.Unsetenv("FORCE_COLOR")
+			t.Setenv("NO_COLOR", "")
+			for k, v := range test.env {
+				t.Setenv(k, v)
+			}
+			logger, output := newCaptureLogger(t, append([]Option{WithStyle(StyleBrief)}, test.opts...)...)
+			logger.Errorln("message")
+			logger.Close()
+
+			text := output()
+			if test.color == "" {
+				if strings.Contains(text, "\x1b[") {
+					t.Errorf("Unexpected color in %q", text)
+				}
+			} else if !strings.HasPrefix(text, test.color+"ERROR") || !strings.Contains(text, levelStyleEnd) {
+				t.Errorf("Unexpected output %q, want color %q", text, test.color)
+			}
+		})
+	}
+}
+
+func TestPrintTarget(t *testing.T) {
+	tests := []struct {
+		target         int
+		stdout, stderr string
+	}{
+		{PrintStdout, "debug\nerror\n", ""},
+		{PrintStderr, "", "debug\nerror\n"},
+		{PrintSplit, "debug\n", "error\n"},
+	}
+	for _, test := range tests {
+		dir := t.TempDir()
+		stdout, err := os.Create(filepath.Join(dir, "stdout"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stderr, err := os.Create(filepath.Join(dir, "stderr"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		os.Stdout, stdout = stdout, os.Stdout
+		os.Stderr, stderr = stderr, os.Stderr
+		logger := New(nil, WithDisableSave(true), WithStyle(StyleBasic), WithLevel(LevelVerBose), WithPrintTarget(test.target))
+		os.Stdout, stdout = stdout, os.Stdout
+		os.Stderr, stderr = stderr, os.Stderr
+		logger.Debugln("debug")
+		logger.Errorln("error")
+		logger.Close()
+		stdout.Close()
+		stderr.Close()
+
+		outText, _ := os.ReadFile(stdout.Name())
+		errText, _ := os.ReadFile(stderr.Name())
+		if string(outText) != test.stdout || string(errText) != test.stderr {
+			t.Errorf("Target %d: unexpected stdout %q and stderr %q", test.target, outText, errText)
+		}
+	}
+}
+
+func TestTimeFormat(t *testing.T) {
+	testDir := t.TempDir()
+	logger, output := newCaptureLogger(t,
+		WithDisableSave(false),
+		WithFileDir(testDir),
+		WithFileName("Test"),
+		WithColor(ColorNever),
+		WithTimeFormat(time.RFC3339Nano),
+		WithTimeZone("+08:00"),
+		WithSaveTimeFormat(TimeUnixMilli),
+	)
+	before := time.Now()
+	logger.Errorln("message")
+	logger.Close()
+
+	text := output()
+	stamp, _, ok := strings.Cut(strings.TrimPrefix(text, "ERROR|"), " ")
+	when, err := time.Parse(time.RFC3339Nano, stamp)
+	if !ok || err != nil || !strings.HasSuffix(stamp, "+08:00") || when.Before(before.Truncate(time.Second)) {
+		t.Errorf("Unexpected console output %q: %v", text, err)
+	}
+
+	saved, err := os.ReadFile(filepath.Join(testDir, "Test.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stamp, _, _ = strings.Cut(strings.TrimPrefix(string(saved), "ERROR|"), " ")
+	if ms, err := strconv.ParseInt(stamp, 10, 64); err != nil || ms < before.UnixMilli() || ms > time.Now().UnixMilli() {
+		t.Errorf("Unexpected log file content %q", saved)
+	}
+
+	// 无效时区返回错误
+	for zone, want := range map[string]string{"UTC": "UTC", "-0530": "-0530", "Asia/Shanghai": "Asia/Shanghai", "Mars/Base": ""} {
+		loc, err := parseTimeZone(zone)
+		if want == "" {
+			if err == nil {
+				t.Errorf("Zone %q: expected error", zone)
+			}
+			continue
+		}
+		if err != nil || loc.String() != want {
+			t.Errorf("Zone %q: unexpected location %v: %v", zone, loc, err)
+		}
+	}
+}
+
+func TestCallerFormat(t *testing.T) {
+	for name, want := range map[string]string{
+		"main.main":                            "main",
+		"example.com/app/api.(*Server).handle": "example.com/app/api",
+		"example.com/app/v2.Run.func1":         "example.com/app/v2",
+		"":                                     "",
+	} {
+		if pkg := funcPackage(name); pkg != want {
+			t.Errorf("funcPackage(%q) = %q, want %q", name, pkg, want)
+		}
+	}
+
+	// 前缀须在路径分隔处结束
+	trims := []struct {
+		trim, file, pkg, want string
+	}{
+		{"/home/u/app", "/home/u/app/x/z.go", "main", "x/z.go"},
+		{"/home/u/app/", "/home/u/app/x/z.go", "main", "x/z.go"},
+		{"/home/u/app", "/home/u/app2/x.go", "main", "/home/u/app2/x.go"},
+		{"example.com/app", "/build/x/z.go", "example.com/app/x", "x/z.go"},
+		{"example.com/app/", "/build/x/z.go", "example.com/app/x", "x/z.go"},
+		{"example.com/app", "/build/app.go", "example.com/app", "app.go"},
+		{"example.com/app", "/build/x/z.go", "example.com/application/x", "/build/x/z.go"},
+	}
+	for _, test := range trims {
+		f := &groCallerFormat{format: CallerFull, trim: test.trim}
+		if got := f.trimPath(test.file, test.pkg); got != test.want {
+			t.Errorf("trimPath(%q, %q) with %q = %q, want %q", test.file, test.pkg, test.trim, got, test.want)
+		}
+	}
+
+	_, file, _, _ := runtime.Caller(0)
+	tests := []struct {
+		opts []Option
+		want string
+	}{
+		{nil, "[grolog_unit_test:"},
+		{[]Option{WithCallerFormat(CallerFile)}, "[grolog_unit_test.go:"},
+		{[]Option{WithCallerFormat(CallerPackage)}, "[github.com/tayne3/grolog/grolog_unit_test.go:"},
+		{[]Option{WithCallerFormat(CallerFull), WithCallerTrim(path.Dir(file) + "/")}, "[grolog_unit_test.go:"},
+		{[]Option{WithCallerFormat(CallerFull), WithCallerTrim("github.com/tayne3/")}, "[grolog/grolog_unit_test.go:"}, // 未使用 -trimpath 时按包导入路径去除
+		{[]Option{WithEnableFunc(true)}, " grolog.TestCallerFormat]"},
+	}
+	for _, test := range tests {
+		testDir := t.TempDir()
+		logger, output := newCaptureLogger(t, append([]Option{
+			WithDisableSave(false),
+			WithFileDir(testDir),
+			WithFileName("Test"),
+			WithStyle(StyleDetail),
+			WithColor(ColorNever),
+			WithSaveCaller(true),
+		}, test.opts...)...)
+		logger.Errorln("message")
+		logger.Close()
+
+		saved, err := os.ReadFile(filepath.Join(testDir, "Test.log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := output(); !strings.Contains(text, test.want) || !strings.Contains(string(saved), test.want) {
+			t.Errorf("Unexpected output %q and log file %q, want %q", text, saved, test.want)
+		}
+	}
+}
+
+func TestStack(t *testing.T) {
+	var records []Record
+	logger, output := newCaptureLogger(t,
+		WithStyle(StyleBasic),
+		WithEnableStack(true),
+		WithStackLevel(LevelError),
+		WithHook(HookFunc(func(r Record) error {
+			records = append(records, r)
+			return nil
+		})),
+	)
+	logger.Warningln("warning")
+	logger.With(Any("id", 1)).Errorln("error")
+	logger.Close()
+
+	lines := strings.Split(output(), "\n")
+	if len(lines) < 5 || lines[0] != "warning" || lines[1] != "error id=1" ||
+		lines[2] != "\tgithub.com/tayne3/grolog.TestStack" || !strings.HasPrefix(lines[3], "\t\t") || !strings.Contains(lines[3], "grolog_unit_test.go:") {
+		t.Errorf("Unexpected output %q", lines)
+	}
+	if len(records) != 2 || records[0].Stack != nil || len(records[1].Stack) == 0 ||
+		!strings.HasPrefix(records[1].Stack[0], "github.com/tayne3/grolog.TestStack ") {
+		t.Errorf("Unexpected record stack: %+v", records)
+	}
+
+	// 转义换行时调用堆栈同样只占一行
+	logger, output = newCaptureLogger(t, WithStyle(StyleBasic), WithEnableStack(true), WithPrintEscape(EscapeAll))
+	logger.Errorln("error")
+	logger.Close()
+	if text := output(); strings.Count(text, "\n") != 1 || !strings.HasPrefix(text, `error\n`+"\tgithub.com/tayne3/grolog.TestStack") {
+		t.Errorf("Unexpected escaped output %q", text)
+	}
+}
+
+// 封装日志器的函数
+func logWrapped(l *Logger, msg string) {
+	l.WithCallerSkip(1).Errorln(msg)
+}
+
+func TestCallerSkip(t *testing.T) {
+	calls := []func(l *Logger){
+		func(l *Logger) { l.VerBose("x") },
+		func(l *Logger) { l.Debug("x") },
+		func(l *Logger) { l.Trace("x") },
+		func(l *Logger) { l.Warning("x") },
+		func(l *Logger) { l.Error("x") },
+		func(l *Logger) { l.Fatal("x") },
+		func(l *Logger) { defer func() { recover() }(); l.Panic("x") },
+		func(l *Logger) { l.VerBoseln("x") },
+		func(l *Logger) { l.Debugln("x") },
+		func(l *Logger) { l.Traceln("x") },
+		func(l *Logger) { l.Warningln("x") },
+		func(l *Logger) { l.Errorln("x") },
+		func(l *Logger) { l.Fatalln("x") },
+		func(l *Logger) { defer func() { recover() }(); l.Panicln("x") },
+		func(l *Logger) { l.VerBosef("x") },
+		func(l *Logger) { l.Debugf("x") },
+		func(l *Logger) { l.Tracef("x") },
+		func(l *Logger) { l.Warningf("x") },
+		func(l *Logger) { l.Errorf("x") },
+		func(l *Logger) { l.Fatalf("x") },
+		func(l *Logger) { defer func() { recover() }(); l.Panicf("x") },
+		func(l *Logger) { l.VerBosew("x") },
+		func(l *Logger) { l.Debugw("x") },
+		func(l *Logger) { l.Tracew("x") },
+		func(l *Logger) { l.Warningw("x", Int("k", 1)) },
+		func(l *Logger) { l.Errorw("x") },
+		func(l *Logger) { l.Fatalw("x") },
+		func(l *Logger) { defer func() { recover() }(); l.Panicw("x") },
+		func(l *Logger) { l.VerBoseCtx(context.Background(), "x") },
+		func(l *Logger) { l.DebugCtx(context.Background(), "x") },
+		func(l *Logger) { l.TraceCtx(context.Background(), "x") },
+		func(l *Logger) { l.WarningCtx(context.Background(), "x") },
+		func(l *Logger) { l.ErrorCtx(context.Background(), "x") },
+		func(l *Logger) { l.FatalCtx(context.Background(), "x") },
+		func(l *Logger) { defer func() { recover() }(); l.PanicCtx(context.Background(), "x") },
+		func(l *Logger) { l.With(Any("k", 1)).Error("x") },
+		func(l *Logger) { l.WithCallerSkip(0).Error("x") },
+		func(l *Logger) { logWrapped(l, "x") },
+		func(l *Logger) { logWrapped(l.WithCallerSkip(1).WithCallerSkip(-1), "x") },
+		func(l *Logger) { l.Caller(0).VerBose("x") },
+		func(l *Logger) { l.Caller(0).Debug("x") },
+		func(l *Logger) { l.Caller(0).Trace("x") },
+		func(l *Logger) { l.Caller(0).Warning("x") },
+		func(l *Logger) { l.Caller(0).Error("x") },
+		func(l *Logger) { l.Caller(0).Fatal("x") },
+		func(l *Logger) { defer func() { recover() }(); l.Caller(0).Panic("x") },
+		func(l *Logger) { l.Caller(0).VerBoseln("x") },
+		func(l *Logger) { l.Caller(0).Debugln("x") },
+		func(l *Logger) { l.Caller(0).Traceln("x") },
+		func(l *Logger) { l.Caller(0).Warningln("x") },
+		func(l *Logger) { l.Caller(0).Errorln("x") },
+		func(l *Logger) { l.Caller(0).Fatalln("x") },
+		func(l *Logger) { defer func() { recover() }(); l.Caller(0).Panicln("x") },
+		func(l *Logger) { l.Caller(0).VerBosef("x") },
+		func(l *Logger) { l.Caller(0).Debugf("x") },
+		func(l *Logger) { l.Caller(0).Tracef("x") },
+		func(l *Logger) { l.Caller(0).Warningf("x") },
+		func(l *Logger) { l.Caller(0).Errorf("x") },
+		func(l *Logger) { l.Caller(0).Fatalf("x") },
+		func(l *Logger) { defer func() { recover() }(); l.Caller(0).Panicf("x") },
+		func(l *Logger) { defer l.Recover(); panic("x") },
+	}
+
+	for _, asyn := range []bool{false, true} {
+		var records []Record
+		logger := New(nil,
+			WithDisablePrint(true),
+			WithDisableSave(true),
+			WithEnableAsyn(asyn),
+			WithLevel(LevelVerBose),
+			WithFatalAction(FatalContinue),
+			WithHook(HookFunc(func(r Record) error {
+				records = append(records, r)
+				return nil
+			})),
+		)
+		for _, call := range calls {
+			call(logger)
+		}
+		logger.Close()
+
+		if len(records) != len(calls) {
+			t.Fatalf("Asyn %v: got %d records, want %d", asyn, len(records), len(calls))
+		}
+		for i, call := range calls {
+			// 每个调用写在单独一行, 调用位置即函数定义所在行
+			file, line := runtime.FuncForPC(reflect.ValueOf(call).Pointer()).FileLine(reflect.ValueOf(call).Pointer())
+			if records[i].File != file || records[i].Line != line {
+				t.Errorf("Asyn %v: call %d reported %s:%d, want %s:%d", asyn, i, records[i].File, records[i].Line, file, line)
+			}
+		}
+	}
+}
+
+// 附带字段和调用堆栈的错误
+type testQueryError struct {
+	table   string
+	callers []uintptr
+}
+
+func (e *testQueryError) Error() string        { return "query failed" }
+func (e *testQueryError) ErrorFields() []Field { return []Field{Any("table", e.table)} }
+func (e *testQueryError) Callers() []uintptr   { return e.callers }
+
+func TestErrorPayload(t *testing.T) {
+	pcs := make([]uintptr, 1)
+	runtime.Callers(1, pcs)
+	base := &testQueryError{table: "users", callers: pcs}
+	err := fmt.Errorf("load: %w", errors.Join(base, errors.New("token=abc")))
+
+	var records []Record
+	logger, output := newCaptureLogger(t,
+		WithStyle(StyleBasic),
+		WithRedact(RedactAPIKey),
+		WithHook(HookFunc(func(r Record) error {
+			records = append(records, r)
+			return nil
+		})),
+	)
+	logger.With(Any("id", 1)).Errorln("request:", err)
+	logger.Close()
+
+	lines := strings.Split(output(), "\n")
+	if len(lines) != 5 || lines[1] != "token=[REDACTED] id=1 table=users" || !strings.HasPrefix(lines[2], "\tgithub.com/tayne3/grolog.TestErrorPayload") {
+		t.Errorf("Unexpected output %q", lines)
+	}
+	if len(records) != 1 || records[0].Error == nil || records[0].Error.Error() != "load: query failed\ntoken=[REDACTED]" || len(records[0].Fields) != 2 || len(records[0].Stack) != 1 {
+		t.Errorf("Unexpected record: %+v", records)
+	}
+
+	// 详细日志写入错误链
+	logger, output = newCaptureLogger(t, WithStyle(StyleDetail), WithColor(ColorNever), WithRedact(RedactAPIKey))
+	logger.Errorf("request: %v", err)
+	logger.Close()
+	text := output()
+	for _, want := range []string{
+		"\n\t*fmt.wrapError: load: query failed\\ntoken=[REDACTED]",
+		"\n\t  caused by *errors.joinError: query failed\\ntoken=[REDACTED]",
+		"\n\t    caused by *grolog.testQueryError: query failed",
+		"\n\t    caused by *errors.errorString: token=[REDACTED]",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Output %q does not contain %q", text, want)
+		}
+	}
+}
+
+// 延迟求值的日志值
+type testValuer struct{ calls *int }
+
+func (v testValuer) LogValue() any {
+	*v.calls++
+	return "valuer"
+}
+
+func TestLazy(t *testing.T) {
+	logger, output := newCaptureLogger(t, WithStyle(StyleBasic), WithLevel(LevelWarning))
+	calls := 0
+	lazy := Lazy(func() any {
+		calls++
+		return "lazy"
+	})
+	args := []any{lazy, testValuer{&calls}}
+	child := logger.With(Any("field", lazy))
+
+	child.Debugln(args...)
+	child.Debugf("%v", func() any { calls++; return nil })
+	if calls != 0 {
+		t.Errorf("Lazy values evaluated %d times for disabled level", calls)
+	}
+	if logger.Enabled(LevelDebug) || !logger.Enabled(LevelWarning) || !logger.Enabled(LevelFatal) {
+		t.Error("Unexpected Enabled result")
+	}
+
+	child.Warningln(args...)
+	logger.Close()
+	if text := output(); text != "lazy valuer field=lazy\n" || calls != 3 {
+		t.Errorf("Unexpected output %q after %d calls", text, calls)
+	}
+	// 调用方的参数和日志器的字段未被修改
+	if _, ok := args[0].(Lazy); !ok {
+		t.Error("Caller arguments modified")
+	}
+	if _, ok := child.fields[0].Value.(Lazy); !ok {
+		t.Error("Logger fields modified")
+	}
+}
+
+func TestFields(t *testing.T) {
+	for _, d := range []time.Duration{0, 1, 999, 1500, 1000000, 1234567, time.Second, 90 * time.Second,
+		time.Hour + time.Millisecond, -2500 * time.Microsecond, 100*time.Hour + 1} {
+		if got := string(appendDuration(nil, d)); got != d.String() {
+			t.Errorf("appendDuration(%d) = %q, want %q", int64(d), got, d.String())
+		}
+	}
+
+	for _, asyn := range []bool{false, true} {
+		var records []Record
+		logger, output := newCaptureLogger(t,
+			WithStyle(StyleBasic),
+			WithEnableAsyn(asyn),
+			WithHook(HookFunc(func(r Record) error {
+				records = append(records, r)
+				return nil
+			})),
+		)
+		payload := []byte("a b")
+		now := time.Date(2025, 1, 2, 3, 4, 5, 6, time.FixedZone("+08:00", 8*3600))
+		child := logger.With(Bytes("base", payload))
+
+		child.Debugw("skipped", Int("n", 1))
+		child.Warningw("done",
+			Int("n", -42),
+			Str("s", "ok"),
+			Bytes("b", payload),
+			Bool("f", false),
+			Dur("d", 1500*time.Millisecond),
+			Time("t", now),
+			Any("lazy", Lazy(func() any { return "v" })),
+		)
+		payload[0] = 'x' // 调用返回后修改不影响日志
+		logger.Close()
+
+		want := `done base="a b" n=-42 s=ok b="a b" f=false d=1.5s t=2025-01-02T03:04:05.000000006+08:00 lazy=v` + "\n"
+		if text := output(); text != want {
+			t.Errorf("Asyn %v: unexpected output %q", asyn, text)
+		}
+		if len(records) != 1 {
+			t.Fatalf("Asyn %v: got %d records", asyn, len(records))
+		}
+		values := []any{[]byte("a b"), int64(-42), "ok", []byte("a b"), false, 1500 * time.Millisecond, now, "v"}
+		for i, f := range records[0].Fields {
+			if !reflect.DeepEqual(f.Value, values[i]) && !(i == 6 && f.Value.(time.Time).Equal(now)) {
+				t.Errorf("Asyn %v: record field %s = %#v, want %#v", asyn, f.Key, f.Value, values[i])
+			}
+		}
+	}
+
+	// 超出纳秒时间戳范围的时间
+	logger, output := newCaptureLogger(t, WithStyle(StyleBasic))
+	future := time.Date(3000, 1, 2, 3, 4, 5, 6, time.UTC)
+	logger.Errorw("range", Time("zero", time.Time{}), Time("future", future), Time("max", maxNanoTime))
+	logger.Close()
+	want := "range zero=0001-01-01T00:00:00Z future=3000-01-02T03:04:05.000000006Z max=" + maxNanoTime.Format(time.RFC3339Nano) + "\n"
+	if text := output(); text != want {
+		t.Errorf("Unexpected output %q, want %q", text, want)
+	}
+}
+
+func TestContext(t *testing.T) {
+	logger, output := newCaptureLogger(t, WithStyle(StyleBasic), WithLevel(LevelTrace))
+	child := logger.With(Str("service", "api"))
+
+	ctx := ContextWithFields(context.Background(), Str("request_id", "r1"))
+	ctx = ContextWithFields(ctx, Str("tenant", "acme"))
+	ctx = child.WithContext(ctx)
+	if FromContext(ctx) != child {
+		t.Error("FromContext returned a different logger")
+	}
+	// 没有日志器时返回丢弃日志的日志器, 不创建日志文件
+	for _, ctx := range []context.Context{context.Background(), nil} {
+		discard := FromContext(ctx)
+		if discard == nil || discard.Enabled(LevelPanic) {
+			t.Fatal("FromContext without a logger returned an enabled logger")
+		}
+		discard.ErrorCtx(ctx, "dropped", Int("n", 1))
+		discard.Errorln("dropped")
+	}
+	if _, err := os.Stat(discardLogger().config.FileDir); !os.IsNotExist(err) {
+		t.Errorf("Discard logger created log dir: %v", err)
+	}
+
+	FromContext(ctx).DebugCtx(ctx, "skipped")
+	FromContext(ctx).TraceCtx(ctx, "handled", Int("status", 200))
+	child.Tracew("plain")
+	logger.Close()
+
+	want := "handled service=api request_id=r1 tenant=acme status=200\nplain service=api\n"
+	if text := output(); text != want {
+		t.Errorf("Unexpected output %q", text)
+	}
+}
+
+// 测试用调用链上下文键
+type testSpanKey struct{}
+
+func TestSpan(t *testing.T) {
+	extractor := SpanExtractorFunc(func(ctx context.Context) (SpanContext, bool) {
+		span, ok := ctx.Value(testSpanKey{}).(SpanContext)
+		return span, ok
+	})
+	logger, output := newCaptureLogger(t,
+		WithStyle(StyleBasic),
+		WithLevel(LevelTrace),
+		WithSpanExtractor(extractor),
+	)
+
+	span := SpanContext{TraceFlags: 1}
+	for i := range span.TraceID {
+		span.TraceID[i] = byte(i)
+	}
+	for i := range span.SpanID {
+		span.SpanID[i] = byte(0xf0 + i)
+	}
+	ctx := ContextWithFields(context.WithValue(context.Background(), testSpanKey{}, span), Str("request_id", "r1"))
+
+	logger.TraceCtx(ctx, "traced", Int("n", 1))
+	logger.TraceCtx(context.Background(), "untraced")
+	logger.Tracew("plain")
+	logger.Close()
+
+	want := "traced request_id=r1 trace_id=000102030405060708090a0b0c0d0e0f span_id=f0f1f2f3f4f5f6f7 trace_flags=01 n=1\n" +
+		"untraced\nplain\n"
+	if text := output(); text != want {
+		t.Errorf("Unexpected output %q", text)
+	}
+}
+
+func TestNamed(t *testing.T) {
+	var names []string
+	logger, output := newCaptureLogger(t,
+		WithStyle(StyleBasic),
+		WithLevel(LevelWarning),
+		WithNamedLevel("db", LevelDebug),
+		WithNamedLevel("db.pool.conn", LevelError),
+		WithNamedLevel("http", LevelPanic+1), // 值无效, 忽略
+		WithHook(HookFunc(func(r Record) error {
+			names = append(names, r.Name)
+			return nil
+		})),
+	)
+	db := logger.Named("db")
+	pool := db.Named("pool").With(Int("size", 4))
+	conn := pool.Named("conn")
+	if pool.Name() != "db.pool" || conn.Name() != "db.pool.conn" || logger.Named("").Name() != "" {
+		t.Errorf("Unexpected names %q, %q", pool.Name(), conn.Name())
+	}
+	if !pool.Enabled(LevelDebug) || pool.Enabled(LevelVerBose) || conn.Enabled(LevelWarning) || logger.Named("http").Enabled(LevelTrace) {
+		t.Error("Unexpected Enabled result")
+	}
+
+	logger.Debugln("root")
+	pool.Debugln("acquired")
+	pool.Tracew("typed")
+	conn.Warningln("slow")
+	conn.Errorln("lost")
+	logger.Warningln("root")
+	logger.Close()
+
+	want := "[db.pool] acquired size=4\n[db.pool] typed size=4\n[db.pool.conn] lost size=4\nroot\n"
+	if text := output(); text != want {
+		t.Errorf("Unexpected output %q", text)
+	}
+	if !reflect.DeepEqual(names, []string{"db.pool", "db.pool", "db.pool.conn", ""}) {
+		t.Errorf("Unexpected record names %q", names)
+	}
+}
+
+func TestVModule(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errs []error
+	logger, output := newCaptureLogger(t,
+		WithStyle(StyleBasic),
+		WithLevel(LevelWarning),
+		WithVModule(" grolog_basic*=error, "+filepath.Base(wd)+"/grolog_unit_test.go=verbose,bad,x=loud,[=debug"),
+		WithErrorHandler(func(err error) { errs = append(errs, err) }),
+	)
+	if len(errs) != 3 {
+		t.Errorf("Got %d errors for invalid rules: %v", len(errs), errs)
+	}
+	if !logger.Enabled(LevelVerBose) || !logger.Named("db").Enabled(LevelDebug) {
+		t.Error("Unexpected Enabled result")
+	}
+
+	sites := 0
+	for i := 0; i < 2; i++ { // 第二次使用缓存
+		logger.VerBoseln("verbose", i)
+		logger.Debugw("typed", Int("i", i))
+		logger.Named("db").Debugf("named %d\n", i)
+		logger.Caller(0).Debug("caller ", i, "\n")
+		if i == 0 {
+			sites = len(*logger.config.vmodule.cache.Load())
+		}
+	}
+	logger.Close()
+
+	want := "verbose 0\ntyped i=0\n[db] named 0\ncaller 0\n" +
+		"verbose 1\ntyped i=1\n[db] named 1\ncaller 1\n"
+	if text := output(); text != want {
+		t.Errorf("Unexpected output %q", text)
+	}
+	if n := len(*logger.config.vmodule.cache.Load()); n == 0 || n != sites {
+		t.Errorf("Got %d cached call sites after the first pass, %d after the second", sites, n)
+	}
+
+	// 匹配的规则优先于命名日志器级别 (提高或降低级别)
+	for rule, enabled := range map[string]bool{"grolog_unit_test=verbose": true, "grolog_unit_test=error": false} {
+		logger, _ := newCaptureLogger(t,
+			WithNamedLevel("quiet", LevelError),
+			WithNamedLevel("loud", LevelVerBose),
+			WithVModule(rule),
+		)
+		logger.Close()
+		if logger.Named("quiet").Enabled(LevelDebug) != enabled || logger.Named("loud").Enabled(LevelDebug) != enabled {
+			t.Errorf("Rule %q: named levels not overridden", rule)
 		}
 	}
 }
@@ -6,23 +6,25 @@
 package grolog
 
 import (
-	"context"
 	"fmt"
 	"runtime"
 	"sync"
 	"sync/atomic"
-	"time"
 )
 
 // 日志处理器-异步
+//
+// 生产者在调用方协程中渲染消息, 写入无锁环形队列; 单个消费者协程按顺序推送消息.
 type groHandlerAsyn struct {
-	config      *Config            // 日志选项 (永不为空)
-	pusher      *groPusher         // 日志推送器 (永不为空)
-	flashCancel context.CancelFunc // 定时刷新结束
-	execCount   atomic.Int32       // 异步执行计数
-	closed      atomic.Bool        // 是否已关闭
-	msgs        chan *groMsg       // 消息队列
-	stop        sync.WaitGroup     // 停止等待
+	config   *Config        // 日志选项 (永不为空)
+	pusher   *groPusher     // 日志推送器 (永不为空)
+	flusher  *groFlusher    // 定时刷新器 (永不为空)
+	closed   atomic.Bool    // 是否已关闭
+	msgs     *groRing       // 消息队列 (空消息表示刷新请求)
+	sleeping atomic.Bool    // 消费者是否休眠
+	wake     chan struct{}  // 消费者唤醒通知
+	done     chan struct{}  // 消费者结束通知
+	stop     sync.WaitGroup // 停止等待
 }
 
 var _ groHandler = (*groHandlerAsyn)(nil)
@@ -30,13 +32,15 @@ var _ groHandler = (*groHandlerAsyn)(nil)
 // 创建异步日志处理器
 func newHandlerAsyn(config *Config) groHandler {
 	h := &groHandlerAsyn{
-		config:    config,
-		pusher:    newPusher(config),
-		execCount: atomic.Int32{},
-		closed:    atomic.Bool{},
-		msgs:      make(chan *groMsg, config.MaxAsynBuffer),
-		stop:      sync.WaitGroup{},
+		config: config,
+		closed: atomic.Bool{},
+		msgs:   newRing(config.MaxAsynBuffer),
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		stop:   sync.WaitGroup{},
 	}
+	h.flusher = config.newFlusher(h.Flush)
+	h.pusher = newPusher(config, h.flusher)
 
 	// 预分配消息对象
 	if config.MaxAsynBuffer > 0 {
@@ -49,11 +53,11 @@ func newHandlerAsyn(config *Config) groHandler {
 		}
 	}
 
+	// 启动消费者
+	h.goHanding()
+
 	// 定时刷新缓冲区
-	duration, _ := time.ParseDuration(h.config.FlashInterval)
-	var ctx context.Context
-	ctx, h.flashCancel = context.WithCancel(context.Background())
-	h.goFlash(duration, ctx)
+	h.flusher.Start()
 
 	return h
 }
@@ -66,11 +70,10 @@ func (h *groHandlerAsyn) Close() {
 		return
 	}
 
-	h.flashCancel()
-	h.msgs <- nil // 未关闭的话, 发送空消息
+	h.flusher.Close()
+	h.enqueue(nil, true) // 发送空消息, 通知消费者结束
 	h.stop.Wait()
 	h.pusher.Close()
-	close(h.msgs)
 }
 
 // 刷新日志处理器
@@ -79,138 +82,178 @@ func (h *groHandlerAsyn) Flush() {
 		return
 	}
 
-	h.msgs <- nil
+	h.enqueue(nil, false)
+}
+
+// 同步日志处理器 (等待已提交的消息推送完成并刷新缓冲区)
+func (h *groHandlerAsyn) Sync() {
+	if h.closed.Load() {
+		return
+	}
+
+	m := &groMsg{sync: make(chan struct{})}
+	if !h.enqueue(m, false) {
+		return
+	}
+	select {
+	case <-m.sync:
+	case <-h.done:
+	}
 }
 
-func (h *groHandlerAsyn) Log(level int, layer int, a ...any) {
-	if h.config.Level > level || h.closed.Load() {
+func (h *groHandlerAsyn) Log(l *Logger, level int, layer int, a ...any) {
+	if !l.enabled(level, callerDepth-1+layer) || h.closed.Load() {
 		return
 	}
+	a, fields := resolveArgs(a), resolveFields(l.fields)
 
 	m := h.pusher.get()
-	h.pusher.assign(m, level, layer)
+	h.pusher.assign(m, level, layer, fields)
+	m.name = l.name
 	fmt.Fprint(m.text, a...)
+	m.err = findError(a, fields)
 
 	h.msgHanding(m)
 }
 
-func (h *groHandlerAsyn) Logln(level int, layer int, a ...any) {
-	if h.config.Level > level || h.closed.Load() {
+func (h *groHandlerAsyn) Logln(l *Logger, level int, layer int, a ...any) {
+	if !l.enabled(level, callerDepth-1+layer) || h.closed.Load() {
 		return
 	}
+	a, fields := resolveArgs(a), resolveFields(l.fields)
 
 	m := h.pusher.get()
-	h.pusher.assign(m, level, layer)
+	h.pusher.assign(m, level, layer, fields)
+	m.name = l.name
 	fmt.Fprintln(m.text, a...)
+	m.err = findError(a, fields)
 
 	h.msgHanding(m)
 }
 
-func (h *groHandlerAsyn) Logf(level int, layer int, format string, args ...any) {
-	if h.config.Level > level || h.closed.Load() {
+func (h *groHandlerAsyn) Logf(l *Logger, level int, layer int, format string, args ...any) {
+	if !l.enabled(level, callerDepth-1+layer) || h.closed.Load() {
 		return
 	}
+	args, fields := resolveArgs(args), resolveFields(l.fields)
 
 	m := h.pusher.get()
-	h.pusher.assign(m, level, layer)
+	h.pusher.assign(m, level, layer, fields)
+	m.name = l.name
 	fmt.Fprintf(m.text, format, args...)
+	m.err = findError(args, fields)
+
+	h.msgHanding(m)
+}
+
+func (h *groHandlerAsyn) Logw(l *Logger, level int, layer int, buf *groFieldBuf, msg string) {
+	if !l.enabled(level, callerDepth-1+layer) || h.closed.Load() {
+		putFieldBuf(buf)
+		return
+	}
+	resolveFieldBuf(buf)
+
+	m := h.pusher.get()
+	h.pusher.assign(m, level, layer, buf.fields)
+	m.name = l.name
+	m.buf = buf
+	m.text.WriteString(msg)
+	m.text.WriteByte('\n')
+	m.err = findError(nil, buf.fields)
 
 	h.msgHanding(m)
 }
 
 // 消息处理
 func (h *groHandlerAsyn) msgHanding(m *groMsg) {
-	retry := 0
-	for {
-		select {
-		case h.msgs <- m:
-			if h.execCount.Load() == 0 {
-				h.execCount.Add(1)
-				h.goHanding()
-			}
+	if !h.enqueue(m, false) {
+		h.pusher.release(m)
+		h.pusher.put(m)
+	}
+}
+
+// 写入消息队列 (队列已满时等待消费者; 已关闭且非强制写入时放弃并返回false)
+func (h *groHandlerAsyn) enqueue(m *groMsg, force bool) bool {
+	for !h.msgs.Push(m) {
+		if !force && h.closed.Load() {
+			return false
+		}
+		h.notify()
+		runtime.Gosched()
+	}
+	h.notify()
+	return true
+}
+
+// 唤醒消费者
+func (h *groHandlerAsyn) notify() {
+	if !h.sleeping.Load() {
+		return
+	}
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+// 等待新消息
+func (h *groHandlerAsyn) await() {
+	// 短暂自旋, 减少频繁休眠唤醒
+	for i := 0; i < 100; i++ {
+		if h.msgs.Ready() {
 			return
-		default:
-			if h.closed.Load() {
-				return
-			}
-			retry++
-			if retry > 3 && h.execCount.Load() < int32(h.config.MaxAsynExec) {
-				h.execCount.Add(1)
-				h.goHanding()
-			}
-			runtime.Gosched()
 		}
+		runtime.Gosched()
+	}
+
+	h.sleeping.Store(true)
+	if !h.msgs.Ready() { // 设置休眠标记后再次检查, 避免丢失唤醒
+		<-h.wake
 	}
+	h.sleeping.Store(false)
 }
 
 // 消息处理
 func (h *groHandlerAsyn) goHanding() {
 	h.stop.Add(1)
-	go func() {
+	h.config.exec.Go(TaskConsumer, func() {
 		defer h.stop.Done()
+		defer close(h.done)
 
-		idle := 0
-		retry := 0
 		for {
-			select {
-			case m := <-h.msgs: // 不断取出消息
-				idle = 0
-				retry++
-				if retry > 100 {
-					runtime.Gosched()
-				}
-				if m != nil {
-					h.pusher.push(m)
-					h.pusher.put(m)
-					break
-				}
-				if h.closed.Load() { // 收到空消息且已关闭, 结束
-					goto Closed
-				}
-				h.pusher.Flush() // 未关闭, 刷新缓冲区
-			default:
-				retry = 0
-				idle++
-				if idle > 100 && h.execCount.Load() > 0 {
-					h.execCount.Add(-1)
-					goto Closed
-				}
-				// runtime.Gosched()
+			m, ok := h.msgs.Pop()
+			if !ok {
+				h.await()
+				continue
 			}
+			if m != nil && m.sync != nil { // 同步请求, 刷新缓冲区后通知
+				h.pusher.Flush()
+				close(m.sync)
+				continue
+			}
+			if m != nil {
+				h.pusher.push(m)
+				h.pusher.put(m)
+				continue
+			}
+			if h.closed.Load() { // 收到空消息且已关闭, 结束
+				break
+			}
+			h.pusher.Flush() // 未关闭, 刷新缓冲区
 		}
-	Closed:
-		if !h.closed.Load() {
-			return
-		}
+
+		// 取出已有的消息
 		for {
-			select {
-			case m := <-h.msgs: // 取出已有的消息
-				if m != nil {
-					h.pusher.push(m)
-					h.pusher.put(m)
-				}
-			default: // 无消息, 结束
-				h.msgs <- nil
+			m, ok := h.msgs.Pop()
+			if !ok {
 				return
 			}
+			if m != nil && m.sync != nil {
+				close(m.sync)
+			} else if m != nil {
+				h.pusher.push(m)
+				h.pusher.put(m)
+			}
 		}
-	}()
-}
-
-// 定时刷新日志
-func (h *groHandlerAsyn) goFlash(interval time.Duration, ctx context.Context) {
-	if interval <= 0 {
-		return
-	}
-
-	h.stop.Add(1)
-	go func() {
-		defer h.stop.Done()
-		select {
-		case <-ctx.Done():
-			return
-		case <-time.After(interval):
-			h.Flush()
-		}
-	}()
+	})
 }
@@ -7,19 +7,16 @@ package grolog
 
 import (
 	"bytes"
-	"context"
 	"fmt"
 	"sync"
-	"time"
 )
 
 // 日志处理器-同步
 type groHandlerSync struct {
-	config      *Config            // 日志选项 (永不为空)
-	pusher      *groPusher         // 日志推送器 (永不为空)
-	stop        sync.WaitGroup     // 停止等待
-	flashCancel context.CancelFunc // 定时刷新结束
-	bufferPool  sync.Pool          // 缓冲区对象池
+	config     *Config     // 日志选项 (永不为空)
+	pusher     *groPusher  // 日志推送器 (永不为空)
+	flusher    *groFlusher // 定时刷新器 (永不为空)
+	bufferPool sync.Pool   // 缓冲区对象池
 }
 
 var _ groHandler = (*groHandlerSync)(nil)
@@ -28,23 +25,20 @@ var _ groHandler = (*groHandlerSync)(nil)
 func newHandlerSync(config *Config) groHandler {
 	h := &groHandlerSync{
 		config:     config,
-		stop:       sync.WaitGroup{},
-		pusher:     newPusher(config),
 		bufferPool: sync.Pool{New: func() any { return new(bytes.Buffer) }},
 	}
+	h.flusher = config.newFlusher(h.Flush)
+	h.pusher = newPusher(config, h.flusher)
 
-	duration, _ := time.ParseDuration(h.config.FlashInterval)
-	var ctx context.Context
-	ctx, h.flashCancel = context.WithCancel(context.Background())
-	h.goFlash(duration, ctx)
+	// 定时刷新缓冲区
+	h.flusher.Start()
 
 	return h
 }
 
 // 关闭日志处理器
 func (h *groHandlerSync) Close() {
-	h.flashCancel()
-	h.stop.Wait()
+	h.flusher.Close()
 	h.pusher.Close()
 }
 
@@ -53,56 +47,70 @@ func (h *groHandlerSync) Flush() {
 	h.pusher.Flush()
 }
 
-func (h *groHandlerSync) Log(level int, layer int, a ...any) {
-	if h.config.Level > level {
+// 同步日志处理器
+func (h *groHandlerSync) Sync() {
+	h.pusher.Flush()
+}
+
+func (h *groHandlerSync) Log(l *Logger, level int, layer int, a ...any) {
+	if !l.enabled(level, callerDepth-1+layer) {
 		return
 	}
+	a, fields := resolveArgs(a), resolveFields(l.fields)
 
 	var m groMsg
-	h.pusher.assign(&m, level, layer)
+	h.pusher.assign(&m, level, layer, fields)
+	m.name = l.name
 	fmt.Fprint(m.text, a...)
+	m.err = findError(a, fields)
 
 	h.pusher.push(&m)
 }
 
-func (h *groHandlerSync) Logln(level int, layer int, a ...any) {
-	if h.config.Level > level {
+func (h *groHandlerSync) Logln(l *Logger, level int, layer int, a ...any) {
+	if !l.enabled(level, callerDepth-1+layer) {
 		return
 	}
+	a, fields := resolveArgs(a), resolveFields(l.fields)
 
 	var m groMsg
-	h.pusher.assign(&m, level, layer)
+	h.pusher.assign(&m, level, layer, fields)
+	m.name = l.name
 	fmt.Fprintln(m.text, a...)
+	m.err = findError(a, fields)
 
 	h.pusher.push(&m)
 }
 
-func (h *groHandlerSync) Logf(level int, layer int, format string, args ...any) {
-	if h.config.Level > level {
+func (h *groHandlerSync) Logf(l *Logger, level int, layer int, format string, args ...any) {
+	if !l.enabled(level, callerDepth-1+layer) {
 		return
 	}
+	args, fields := resolveArgs(args), resolveFields(l.fields)
 
 	var m groMsg
-	h.pusher.assign(&m, level, layer)
+	h.pusher.assign(&m, level, layer, fields)
+	m.name = l.name
 	fmt.Fprintf(m.text, format, args...)
+	m.err = findError(args, fields)
 
 	h.pusher.push(&m)
 }
 
-// 定时刷新日志
-func (h *groHandlerSync) goFlash(interval time.Duration, ctx context.Context) {
-	if interval <= 0 {
+func (h *groHandlerSync) Logw(l *Logger, level int, layer int, buf *groFieldBuf, msg string) {
+	if !l.enabled(level, callerDepth-1+layer) {
+		putFieldBuf(buf)
 		return
 	}
+	resolveFieldBuf(buf)
 
-	h.stop.Add(1)
-	go func() {
-		defer h.stop.Done()
-		select {
-		case <-ctx.Done():
-			return
-		case <-time.After(interval):
-			h.Flush()
-		}
-	}()
+	var m groMsg
+	h.pusher.assign(&m, level, layer, buf.fields)
+	m.name = l.name
+	m.buf = buf
+	m.text.WriteString(msg)
+	m.text.WriteByte('\n')
+	m.err = findError(nil, buf.fields)
+
+	h.pusher.push(&m)
 }
@@ -0,0 +1,119 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 日志记录 (只读, 钩子不得修改)
+type Record struct {
+	Level    int       // 日志级别
+	Name     string    // 日志器名称 (未命名时为空)
+	Time     time.Time // 记录时间
+	File     string    // 调用文件 (完整路径)
+	Line     int       // 调用行号
+	Function string    // 调用函数 (完整名称)
+	Message  string    // 日志消息 (不含结尾换行)
+	Fields   []Field   // 附加字段 (包括错误链中 ErrorFielder 的字段)
+	Error    error     // 日志参数或附加字段中的首个错误 (没有错误时为空, 错误信息需要脱敏时替换为仅包含脱敏后信息的错误)
+	Stack    []string  // 调用堆栈 (错误附带调用堆栈, 或启用调用堆栈且达到级别时有效, 每个调用帧格式: 函数名称 文件:行号)
+}
+
+// 日志钩子
+type Hook interface {
+	Fire(r Record) error // 处理日志记录 (返回的错误通过日志器的错误处理报告)
+}
+
+// 函数钩子
+type HookFunc func(r Record) error
+
+// 处理日志记录
+func (f HookFunc) Fire(r Record) error {
+	return f(r)
+}
+
+// 已注册的钩子
+type groHook struct {
+	hook  Hook   // 日志钩子
+	asyn  bool   // 是否异步执行
+	level uint32 // 触发级别掩码
+}
+
+// 创建已注册的钩子 (触发级别为空时所有级别均触发)
+func newHook(hook Hook, asyn bool, levels []int) groHook {
+	h := groHook{hook: hook, asyn: asyn}
+	if len(levels) == 0 {
+		h.level = ^uint32(0)
+	}
+	for _, level := range levels {
+		h.level |= 1 << uint(level)
+	}
+	return h
+}
+
+// 是否在指定级别触发
+func (h *groHook) enabled(level int) bool {
+	return h.level&(1<<uint(level)) != 0
+}
+
+// 执行钩子 (返回钩子的错误)
+func (h *groHook) fire(r Record) error {
+	if err := h.hook.Fire(r); err != nil {
+		return fmt.Errorf("hook: %w", err)
+	}
+	return nil
+}
+
+// 钩子队列 (异步模式下在消费者协程之外按顺序执行同步钩子, 钩子可以记录日志或调用 Sync 而不等待消费者自身)
+//
+// 有待执行的钩子时通过执行器启动一个任务, 依次执行直到队列为空; 队列不限长度, 消费者不会因钩子阻塞.
+type groHookQueue struct {
+	exec    *groExecutor // 执行器
+	mutex   sync.Mutex   // 队列锁
+	tasks   []func()     // 待执行的钩子
+	running bool         // 是否已启动执行任务
+}
+
+// 创建钩子队列
+func newHookQueue(exec *groExecutor) *groHookQueue {
+	return &groHookQueue{exec: exec}
+}
+
+// 添加待执行的钩子
+func (q *groHookQueue) Go(f func()) {
+	q.mutex.Lock()
+	q.tasks = append(q.tasks, f)
+	if q.running {
+		q.mutex.Unlock()
+		return
+	}
+	q.running = true
+	q.mutex.Unlock()
+
+	q.exec.Go(TaskCallback, q.run)
+}
+
+// 按顺序执行钩子 (队列为空时结束)
+func (q *groHookQueue) run() {
+	for {
+		q.mutex.Lock()
+		tasks := q.tasks
+		q.tasks = nil
+		if len(tasks) == 0 {
+			q.running = false
+			q.mutex.Unlock()
+			return
+		}
+		q.mutex.Unlock()
+
+		for _, f := range tasks {
+			f()
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+// 延迟求值接口 (作为日志参数或字段值时, 只在日志通过级别检查后调用)
+type LogValuer interface {
+	LogValue() any
+}
+
+// 延迟求值函数
+type Lazy func() any
+
+// 计算日志值
+func (f Lazy) LogValue() any {
+	return f()
+}
+
+// 延迟求值嵌套上限 (防止 LogValue 返回自身时无限循环)
+const maxLazyDepth = 8
+
+// 计算延迟求值的日志值 (返回值不是延迟求值或达到嵌套上限时返回)
+func resolveValue(v any) (any, bool) {
+	resolved := false
+	for i := 0; i < maxLazyDepth; i++ {
+		switch lazy := v.(type) {
+		case LogValuer:
+			v = lazy.LogValue()
+		case func() any:
+			v = lazy()
+		default:
+			return v, resolved
+		}
+		resolved = true
+	}
+	return v, resolved
+}
+
+// 计算日志参数中延迟求值的值 (包含延迟求值时返回副本, 不修改调用方的切片)
+func resolveArgs(a []any) []any {
+	copied := false
+	for i, v := range a {
+		value, ok := resolveValue(v)
+		if !ok {
+			continue
+		}
+		if !copied {
+			a = append([]any(nil), a...)
+			copied = true
+		}
+		a[i] = value
+	}
+	return a
+}
+
+// 计算附加字段中延迟求值的值 (包含延迟求值时返回副本, 附加字段与日志器共享)
+func resolveFields(fields []Field) []Field {
+	copied := false
+	for i, f := range fields {
+		value, ok := resolveValue(f.Value)
+		if !ok {
+			continue
+		}
+		if !copied {
+			fields = append([]Field(nil), fields...)
+			copied = true
+		}
+		fields[i].Value = value
+	}
+	return fields
+}
+
+// 计算字段缓冲区中延迟求值的值 (字段缓冲区归日志消息所有, 直接修改)
+func resolveFieldBuf(fb *groFieldBuf) {
+	for i, f := range fb.fields {
+		if value, ok := resolveValue(f.Value); ok {
+			fb.fields[i].Value = value
+		}
+	}
+}
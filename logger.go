@@ -5,19 +5,34 @@
 
 package grolog
 
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
 // 日志处理器
 type groHandler interface {
 	Flush()
+	Sync()
 	Close()
-	Log(level int, layer int, a ...any)
-	Logln(level int, layer int, a ...any)
-	Logf(level int, layer int, format string, args ...any)
+	Log(l *Logger, level int, layer int, a ...any)
+	Logln(l *Logger, level int, layer int, a ...any)
+	Logf(l *Logger, level int, layer int, format string, args ...any)
+	Logw(l *Logger, level int, layer int, buf *groFieldBuf, msg string)
 }
 
 // 日志器
 type Logger struct {
-	config  Config     // 日志配置
-	handler groHandler // 日志处理器
+	config  *Config    // 日志配置 (与派生日志器共享)
+	handler groHandler // 日志处理器 (与派生日志器共享)
+	fields  []Field    // 附加字段 (创建后不再修改)
+	skip    int        // 调用位置额外跳过的调用层级
+	name    string     // 日志器名称 (未命名时为空)
+	level   int        // 日志级别 (命名日志器使用最近的已配置祖先的级别)
 }
 
 // 创建日志器
@@ -25,16 +40,19 @@ func New(cfg *Config, opts ...Option) (l *Logger) {
 	if cfg == nil {
 		cfg = DefaultConfig()
 	}
-	l = &Logger{config: *cfg}
+	config := *cfg
+	l = &Logger{config: &config}
 	for _, opt := range opts {
-		opt(&l.config)
+		opt(l.config)
 	}
 	l.config.init(l)
+	l.config.exec.Start()
+	l.level = l.config.Level
 
 	if l.config.EnableAsyn {
-		l.handler = newHandlerAsyn(&l.config)
+		l.handler = newHandlerAsyn(l.config)
 	} else {
-		l.handler = newHandlerSync(&l.config)
+		l.handler = newHandlerSync(l.config)
 	}
 	return l
 }
@@ -42,23 +60,26 @@ func New(cfg *Config, opts ...Option) (l *Logger) {
 // 创建日志器 (使用默认配置)
 func Default() (l *Logger) {
 	l = &Logger{
-		config: *DefaultConfig(),
+		config: DefaultConfig(),
 	}
 	l.config.init(l)
+	l.config.exec.Start()
+	l.level = l.config.Level
 
 	if l.config.EnableAsyn {
-		l.handler = newHandlerAsyn(&l.config)
+		l.handler = newHandlerAsyn(l.config)
 	} else {
-		l.handler = newHandlerSync(&l.config)
+		l.handler = newHandlerSync(l.config)
 	}
 	return l
 }
 
-// 关闭日志器
+// 关闭日志器 (等待全部后台任务结束)
 func (l *Logger) Close() {
 	if l.handler != nil {
 		l.handler.Close()
 	}
+	l.config.exec.Close()
 }
 
 // 刷新日志器
@@ -66,82 +87,299 @@ func (l *Logger) Flush() {
 	l.handler.Flush()
 }
 
+// 获取最近一次内部错误 (如写入日志文件失败, 无错误时返回nil)
+func (l *Logger) Err() error {
+	return l.config.errs.Get()
+}
+
+// 获取内存备用缓冲区中保留的日志 (备用输出链不包含内存时返回nil)
+func (l *Logger) FallbackLogs() []byte {
+	if l.config.memory == nil {
+		return nil
+	}
+	return l.config.memory.Bytes()
+}
+
+// 创建附加字段的派生日志器 (与当前日志器共享配置和输出)
+func (l *Logger) With(fields ...Field) *Logger {
+	child := *l
+	child.fields = make([]Field, 0, len(l.fields)+len(fields))
+	child.fields = append(child.fields, l.fields...)
+	child.fields = append(child.fields, fields...)
+	for i, f := range child.fields[len(l.fields):] {
+		if f.kind == fieldBytes { // 字节切片字段可能被调用方修改, 复制数据
+			child.fields[len(l.fields)+i].str = strings.Clone(f.str)
+		}
+	}
+	return &child
+}
+
+// 捕获异常 (需使用 defer 调用)
+//
+// 捕获到异常时, 同步记录包含异常值和调用堆栈的致命日志并刷新缓冲区, 然后以异常值调用异常日志处理函数
+// (默认执行致命错误动作, 致命错误动作为抛出异常时重新抛出).
+func (l *Logger) Recover() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	l.recovered(r)
+	l.config.FatalHandling(l, r)
+}
+
+// 捕获异常并退出程序 (需使用 defer 调用)
+//
+// 捕获到异常时, 同步记录包含异常值和调用堆栈的致命日志, 关闭日志器后以指定退出码退出程序.
+func (l *Logger) RecoverAndExit(code int) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	l.recovered(r)
+	l.Close()
+	os.Exit(code)
+}
+
+// 记录捕获的异常
+func (l *Logger) recovered(r any) {
+	l.handler.Logf(l, LevelFatal, panicLayer(), "panic: %v\n%s", r, debug.Stack())
+	l.handler.Sync()
+}
+
+// 获取异常发生处相对 recovered 调用者的调用层级
+//
+// 调用链: recovered -> Recover -> runtime.gopanic -> [runtime 内部函数] -> 异常发生处
+func panicLayer() int {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	panicking := false
+	for layer := 0; ; layer++ {
+		frame, more := frames.Next()
+		if panicking && !strings.HasPrefix(frame.Function, "runtime.") {
+			return layer
+		}
+		if frame.Function == "runtime.gopanic" {
+			panicking = true
+		}
+		if !more {
+			return 1
+		}
+	}
+}
+
+// 致命错误处理 (等待日志写入并刷新后, 以脱敏后的消息调用异常日志处理函数)
+func (l *Logger) fatal(msg string) {
+	l.handler.Sync()
+	l.config.FatalHandling(l, l.config.redactor.text(strings.TrimSuffix(msg, "\n")))
+}
+
+// 异常错误处理 (等待日志写入并刷新后, 以脱敏后的消息抛出异常)
+func (l *Logger) panic(msg string) {
+	l.handler.Sync()
+	panic(l.config.redactor.text(strings.TrimSuffix(msg, "\n")))
+}
+
+// 是否记录指定级别的日志 (用于跳过只为日志准备数据的代码, 配置调用位置级别时按调用处判断)
+func (l *Logger) Enabled(level int) bool {
+	return l.enabled(level, 1)
+}
+
+// 是否记录指定级别的日志 (skip 为调用处相对 enabled 调用者的调用层级)
+func (l *Logger) enabled(level int, skip int) bool {
+	if l.config.vmodule == nil {
+		return level >= l.level
+	}
+	// 调用层级: runtime.Callers -> enabled -> enabled 的调用者 -> ... -> 调用处
+	var pcs [1]uintptr
+	if runtime.Callers(2+skip, pcs[:]) == 0 {
+		return level >= l.level
+	}
+	return level >= l.config.vmodule.level(pcs[0], l.level)
+}
+
+// 创建命名的派生日志器 (名称以点号连接在当前日志器名称之后, 与当前日志器共享配置和输出)
+//
+// 日志级别使用配置中最近的已配置祖先的级别, 如 db.pool 未配置时使用 db 的级别, 均未配置时使用日志级别;
+// 调用位置匹配调用位置级别规则时, 规则的级别优先.
+func (l *Logger) Named(name string) *Logger {
+	child := *l
+	switch {
+	case name == "":
+	case l.name == "":
+		child.name = name
+	default:
+		child.name = l.name + "." + name
+	}
+	child.level = l.config.namedLevel(child.name)
+	return &child
+}
+
+// 获取日志器名称 (未命名时为空)
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// 创建跳过额外调用层级的派生日志器 (用于封装日志器的函数, 调用位置指向封装函数的调用处, 可叠加)
+func (l *Logger) WithCallerSkip(skip int) *Logger {
+	child := *l
+	child.skip += skip
+	return &child
+}
+
 // 获取调用信息
+//
+// Deprecated: 使用 WithCallerSkip 代替.
 func (l *Logger) Caller(layer int) Caller {
 	return Caller{
+		logger:  l,
 		handler: l.handler,
-		layer:   layer,
+		layer:   l.skip + layer,
 	}
 }
 
 func (l *Logger) VerBose(a ...any) {
-	l.handler.Log(LevelVerBose, 0, a...)
+	l.handler.Log(l, LevelVerBose, l.skip, a...)
 }
 
 func (l *Logger) Debug(a ...any) {
-	l.handler.Log(LevelDebug, 0, a...)
+	l.handler.Log(l, LevelDebug, l.skip, a...)
 }
 
 func (l *Logger) Trace(a ...any) {
-	l.handler.Log(LevelTrace, 0, a...)
+	l.handler.Log(l, LevelTrace, l.skip, a...)
 }
 
 func (l *Logger) Warning(a ...any) {
-	l.handler.Log(LevelWarning, 0, a...)
+	l.handler.Log(l, LevelWarning, l.skip, a...)
 }
 
 func (l *Logger) Error(a ...any) {
-	l.handler.Log(LevelError, 0, a...)
+	l.handler.Log(l, LevelError, l.skip, a...)
 }
 
 func (l *Logger) Fatal(a ...any) {
-	l.handler.Log(LevelFatal, 0, a...)
+	a = resolveArgs(a)
+	l.handler.Log(l, LevelFatal, l.skip, a...)
+	msg := fmt.Sprint(a...)
+	l.fatal(msg)
+}
+
+func (l *Logger) Panic(a ...any) {
+	a = resolveArgs(a)
+	l.handler.Log(l, LevelPanic, l.skip, a...)
+	msg := fmt.Sprint(a...)
+	l.panic(msg)
 }
 
 func (l *Logger) VerBoseln(a ...any) {
-	l.handler.Logln(LevelVerBose, 0, a...)
+	l.handler.Logln(l, LevelVerBose, l.skip, a...)
 }
 
 func (l *Logger) Debugln(a ...any) {
-	l.handler.Logln(LevelDebug, 0, a...)
+	l.handler.Logln(l, LevelDebug, l.skip, a...)
 }
 
 func (l *Logger) Traceln(a ...any) {
-	l.handler.Logln(LevelTrace, 0, a...)
+	l.handler.Logln(l, LevelTrace, l.skip, a...)
 }
 
 func (l *Logger) Warningln(a ...any) {
-	l.handler.Logln(LevelWarning, 0, a...)
+	l.handler.Logln(l, LevelWarning, l.skip, a...)
 }
 
 func (l *Logger) Errorln(a ...any) {
-	l.handler.Logln(LevelError, 0, a...)
+	l.handler.Logln(l, LevelError, l.skip, a...)
 }
 
 func (l *Logger) Fatalln(a ...any) {
-	l.handler.Logln(LevelFatal, 0, a...)
+	a = resolveArgs(a)
+	l.handler.Logln(l, LevelFatal, l.skip, a...)
+	msg := fmt.Sprintln(a...)
+	l.fatal(msg)
+}
+
+func (l *Logger) Panicln(a ...any) {
+	a = resolveArgs(a)
+	l.handler.Logln(l, LevelPanic, l.skip, a...)
+	msg := fmt.Sprintln(a...)
+	l.panic(msg)
 }
 
 func (l *Logger) VerBosef(format string, args ...any) {
-	l.handler.Logf(LevelVerBose, 0, format, args...)
+	l.handler.Logf(l, LevelVerBose, l.skip, format, args...)
 }
 
 func (l *Logger) Debugf(format string, args ...any) {
-	l.handler.Logf(LevelDebug, 0, format, args...)
+	l.handler.Logf(l, LevelDebug, l.skip, format, args...)
 }
 
 func (l *Logger) Tracef(format string, args ...any) {
-	l.handler.Logf(LevelTrace, 0, format, args...)
+	l.handler.Logf(l, LevelTrace, l.skip, format, args...)
 }
 
 func (l *Logger) Warningf(format string, args ...any) {
-	l.handler.Logf(LevelWarning, 0, format, args...)
+	l.handler.Logf(l, LevelWarning, l.skip, format, args...)
 }
 
 func (l *Logger) Errorf(format string, args ...any) {
-	l.handler.Logf(LevelError, 0, format, args...)
+	l.handler.Logf(l, LevelError, l.skip, format, args...)
 }
 
 func (l *Logger) Fatalf(format string, args ...any) {
-	l.handler.Logf(LevelFatal, 0, format, args...)
+	args = resolveArgs(args)
+	l.handler.Logf(l, LevelFatal, l.skip, format, args...)
+	msg := fmt.Sprintf(format, args...)
+	l.fatal(msg)
+}
+
+func (l *Logger) Panicf(format string, args ...any) {
+	args = resolveArgs(args)
+	l.handler.Logf(l, LevelPanic, l.skip, format, args...)
+	msg := fmt.Sprintf(format, args...)
+	l.panic(msg)
+}
+
+// 记录带字段的日志 (字段复制到对象池的缓冲区, 类型化字段不分配内存; 上下文为空时不添加上下文字段和调用链字段)
+func (l *Logger) logw(ctx context.Context, level int, msg string, fields []Field) {
+	if !l.enabled(level, 2+l.skip) {
+		return
+	}
+
+	// 依次添加日志器字段、上下文字段、调用链字段和调用字段
+	buf := getFieldBuf()
+	buf.append(l.fields)
+	buf.append(contextFields(ctx))
+	buf.appendSpan(ctx, l.config.SpanExtractor)
+	buf.appendCopy(fields)
+	l.handler.Logw(l, level, l.skip+1, buf, msg)
+}
+
+func (l *Logger) VerBosew(msg string, fields ...Field) {
+	l.logw(nil, LevelVerBose, msg, fields)
+}
+
+func (l *Logger) Debugw(msg string, fields ...Field) {
+	l.logw(nil, LevelDebug, msg, fields)
+}
+
+func (l *Logger) Tracew(msg string, fields ...Field) {
+	l.logw(nil, LevelTrace, msg, fields)
+}
+
+func (l *Logger) Warningw(msg string, fields ...Field) {
+	l.logw(nil, LevelWarning, msg, fields)
+}
+
+func (l *Logger) Errorw(msg string, fields ...Field) {
+	l.logw(nil, LevelError, msg, fields)
+}
+
+func (l *Logger) Fatalw(msg string, fields ...Field) {
+	l.logw(nil, LevelFatal, msg, fields)
+	l.fatal(msg)
+}
+
+func (l *Logger) Panicw(msg string, fields ...Field) {
+	l.logw(nil, LevelPanic, msg, fields)
+	l.panic(msg)
 }
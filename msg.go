@@ -7,9 +7,7 @@ package grolog
 
 import (
 	"bytes"
-	"path/filepath"
 	"runtime"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -17,44 +15,122 @@ import (
 
 // 日志消息
 type groMsg struct {
-	level int
-	tips  *bytes.Buffer
-	stack *bytes.Buffer
-	text  *bytes.Buffer
+	level  int
+	name   string    // 日志器名称 (未命名时为空)
+	time   time.Time // 记录时间 (不需要时为零值)
+	pc     uintptr   // 调用位置 (不需要或获取失败时为0)
+	file   string    // 调用文件
+	line   int       // 调用行号
+	fields []Field   // 附加字段
+	trace  []uintptr // 调用堆栈 (未启用时为空)
+	err    error     // 日志参数或附加字段中的首个错误
+	causes []string  // 错误链 (详细日志有效)
+	tips   *bytes.Buffer
+	stack  *bytes.Buffer
+	text   *bytes.Buffer
+	sync   chan struct{} // 同步请求 (非空时表示同步请求, 处理完成后关闭)
+	buf    *groFieldBuf  // 字段缓冲区 (字段日志方法有效, 释放消息时回收)
+}
+
+// 日志器方法到填充消息的调用层级 (pusher.assign -> handler.Log -> Logger.X -> 调用处)
+//
+// 所有日志器方法、调用器方法和 recovered 都直接调用处理器, 保证调用层级相同;
+// 字段日志方法经 Logger.logw 调用处理器, 多跳过一层.
+const callerDepth = 3
+
+// 获取调用信息
+func (m *groMsg) initCaller(layer int) {
+	// 调用层级: initCaller -> pusher.assign -> handler.Log -> Logger.X -> 调用处
+	if pc, file, line, ok := runtime.Caller(1 + callerDepth + layer); ok {
+		m.pc, m.file, m.line = pc, file, line
+	} else {
+		m.pc, m.file, m.line = 0, "", 0
+	}
 }
 
 // 填充基本日志消息
-func (m *groMsg) initBasic(int) {
+func (m *groMsg) initBasic() {
 }
 
 // 填充简要日志消息
-func (m *groMsg) initBrief(int) {
-	m.tips.WriteString(levelStrings[m.level])
-	m.tips.WriteString("|")
-	{
-		time := time.Now().Format("06.01.02-15:04:05.000")
-		m.tips.WriteString(time)
-	}
+func (m *groMsg) initBrief(tf *groTimeFormat) {
+	m.writeTips(m.tips, tf)
 }
 
 // 填充详细日志消息
-func (m *groMsg) initDetailed(layer int) {
-	m.tips.WriteString(levelStrings[m.level])
-	m.tips.WriteString("|")
-	{
-		time := time.Now().Format("06.01.02-15:04:05.000")
-		m.tips.WriteString(time)
+func (m *groMsg) initDetailed(tf *groTimeFormat, cf *groCallerFormat) {
+	m.writeTips(m.tips, tf)
+	cf.write(m.stack, m.pc, m.file, m.line)
+}
+
+// 写入日志提示 (级别和时间)
+func (m *groMsg) writeTips(buf *bytes.Buffer, tf *groTimeFormat) {
+	buf.WriteString(levelStrings[m.level])
+	buf.WriteString("|")
+	tf.write(buf, m.time)
+}
+
+// 写入消息正文 (日志器名称写在消息之前, 附加字段写在消息之后、结尾换行之前, 详细日志写入错误链)
+func (m *groMsg) writeBody(buf *bytes.Buffer, escape int, detail bool) {
+	if m.name != "" {
+		buf.WriteByte('[')
+		writeEscaped(buf, []byte(m.name), max(escape, EscapeAll))
+		buf.WriteString("] ")
 	}
 
-	m.stack.WriteString("[")
-	if _, file, line, ok := runtime.Caller(4 + layer); !ok {
-		m.stack.WriteString("???#?")
-	} else {
-		m.stack.WriteString(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
-		m.stack.WriteString(":")
-		m.stack.WriteString(strconv.Itoa(line))
+	text := m.text.Bytes()
+	chain := detail && len(m.causes) > 0
+	if len(m.fields) == 0 && len(m.trace) == 0 && !chain {
+		writeEscaped(buf, text, escape)
+		return
+	}
+
+	newline := len(text) > 0 && text[len(text)-1] == '\n'
+	if newline {
+		text = text[:len(text)-1]
+	}
+	writeEscaped(buf, text, escape)
+	for _, f := range m.fields {
+		buf.WriteByte(' ')
+		writeField(buf, f)
+	}
+	if chain {
+		writeErrorChain(buf, m.causes, escape)
+	}
+	if len(m.trace) > 0 {
+		writeTrace(buf, m.trace, escape)
+	}
+	if newline {
+		buf.WriteByte('\n')
+	}
+}
+
+// 生成日志记录
+func (m *groMsg) record() Record {
+	r := Record{
+		Level:   m.level,
+		Name:    m.name,
+		Time:    m.time,
+		File:    m.file,
+		Line:    m.line,
+		Message: strings.TrimSuffix(m.text.String(), "\n"),
+		Error:   m.err,
+	}
+	if m.pc != 0 {
+		if fn := runtime.FuncForPC(m.pc); fn != nil {
+			r.Function = fn.Name()
+		}
+	}
+	if len(m.fields) > 0 {
+		r.Fields = make([]Field, len(m.fields))
+		for i, f := range m.fields {
+			r.Fields[i] = Any(f.Key, f.value())
+		}
+	}
+	if len(m.trace) > 0 {
+		r.Stack = traceStrings(m.trace)
 	}
-	m.stack.WriteString("]")
+	return r
 }
 
 // 缓存对象池
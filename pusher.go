@@ -6,37 +6,46 @@
 package grolog
 
 import (
-	"os"
-	"sync"
+	"bytes"
+	"fmt"
+	"sync/atomic"
+	"time"
 )
 
 // 日志推送器
 type groPusher struct {
 	config     *Config       // 日志选项
-	out        *os.File      // 打印输出
+	out        *groConsole   // 打印输出 (低于警告级别, 禁用日志打印时为空)
+	errOut     *groConsole   // 打印输出 (警告及以上级别, 禁用日志打印时为空)
 	storage    *groStorage   // 日志存储
 	closed     bool          // 是否已关闭
+	outFailed  atomic.Bool   // 打印输出是否失败 (仅在首次失败时报告错误)
+	hooked     bool          // 是否注册了日志钩子
+	hooks      *groHookQueue // 同步钩子队列 (仅异步模式下注册了日志钩子时有效)
 	msgPool    groMsgPool    // 消息对象池
 	bufferPool groBufferPool // 缓冲区对象池
-	lock       sync.Mutex    // 打印锁
 }
 
 // 创建新的推送器
-func newPusher(config *Config) *groPusher {
+func newPusher(config *Config, flusher *groFlusher) *groPusher {
 	p := &groPusher{
 		config:     config,
 		out:        nil,
 		storage:    nil,
 		closed:     false,
+		hooked:     len(config.hooks) > 0,
 		msgPool:    groMsgPool{},
 		bufferPool: groBufferPool{},
 	}
 
+	if p.hooked && config.EnableAsyn {
+		p.hooks = newHookQueue(config.exec)
+	}
 	if !p.config.DisablePrint {
-		p.out = os.Stdout
+		p.out, p.errOut = newConsoles(config)
 	}
 	if !p.config.DisableSave {
-		p.storage = newStorage(config)
+		p.storage = newStorage(config, flusher)
 	}
 
 	p.msgPool.Init()
@@ -51,7 +60,10 @@ func (p *groPusher) Flush() {
 		return
 	}
 	if p.out != nil {
-		p.out.Sync()
+		p.out.out.Sync()
+		if p.errOut != p.out {
+			p.errOut.out.Sync()
+		}
 	}
 	if p.storage != nil {
 		p.storage.Flush()
@@ -80,22 +92,37 @@ func (p *groPusher) put(m *groMsg) {
 }
 
 // 填充消息
-func (p *groPusher) assign(m *groMsg, level int, layer int) {
-	if m.level != level {
-		m.level = level
+func (p *groPusher) assign(m *groMsg, level int, layer int, fields []Field) {
+	m.level = level
+	m.fields = fields
+
+	if p.config.Style != StyleBasic || p.hooked {
+		m.time = time.Now()
+	} else {
+		m.time = time.Time{}
+	}
+	if p.config.Style == StyleDetail || p.hooked {
+		m.initCaller(layer)
+	} else {
+		m.pc, m.file, m.line = 0, "", 0
+	}
+	if p.config.EnableStack && level >= p.config.StackLevel {
+		m.initTrace(layer)
+	} else {
+		m.trace = m.trace[:0]
 	}
 
 	switch p.config.Style {
 	case StyleBasic:
 		m.text = p.bufferPool.Get()
 		m.text.Reset()
-		m.initBasic(layer)
+		m.initBasic()
 	case StyleBrief:
 		m.text = p.bufferPool.Get()
 		m.tips = p.bufferPool.Get()
 		m.text.Reset()
 		m.tips.Reset()
-		m.initBrief(layer)
+		m.initBrief(p.config.printTime)
 	case StyleDetail:
 		m.text = p.bufferPool.Get()
 		m.tips = p.bufferPool.Get()
@@ -103,7 +130,7 @@ func (p *groPusher) assign(m *groMsg, level int, layer int) {
 		m.text.Reset()
 		m.tips.Reset()
 		m.stack.Reset()
-		m.initDetailed(layer)
+		m.initDetailed(p.config.printTime, p.config.caller)
 	}
 }
 
@@ -113,56 +140,153 @@ func (p *groPusher) push(m *groMsg) {
 		return
 	}
 
-	if p.out != nil {
-		switch p.config.Style {
-		case StyleBasic:
-			p.out.Write(m.text.Bytes())
-		case StyleBrief:
-			p.lock.Lock()
-			p.out.WriteString(levelStyleStarts[m.level])
-			p.out.Write(m.tips.Bytes())
-			p.out.WriteString(levelStyleEnd)
-			p.out.WriteString(" ")
-			p.out.Write(m.text.Bytes())
-			p.lock.Unlock()
-		case StyleDetail:
-			p.lock.Lock()
-			p.out.WriteString(levelStyleStarts[m.level])
-			p.out.Write(m.tips.Bytes())
-			p.out.WriteString(levelStyleEnd)
-			p.out.WriteString(" ")
-			p.out.Write(m.stack.Bytes())
-			p.out.WriteString(" ")
-			p.out.Write(m.text.Bytes())
-			p.lock.Unlock()
+	if m.err != nil {
+		m.expandError(p.config.Style == StyleDetail)
+	}
+
+	// 脱敏后再交给任何输出
+	if p.config.redactor != nil {
+		p.config.redactor.apply(m)
+	}
+
+	if p.out != nil || p.storage != nil {
+		line := p.bufferPool.Get()
+		if p.out != nil {
+			line.Reset()
+			p.render(line, m, true)
+			p.print(p.console(m.level), line.Bytes())
+		}
+		if p.storage != nil {
+			line.Reset()
+			p.render(line, m, false)
+			p.storage.Write(line.Bytes())
 		}
+		line.Reset()
+		p.bufferPool.Put(line)
 	}
 
-	if p.storage != nil {
-		switch p.config.Style {
-		case StyleBasic:
-			p.storage.Write(m.text.Bytes())
-		case StyleBrief:
-			p.lock.Lock()
-			p.storage.Write(m.tips.Bytes())
-			p.storage.WriteString(" ")
-			p.storage.Write(m.text.Bytes())
-			p.lock.Unlock()
-		case StyleDetail:
-			p.lock.Lock()
-			p.storage.Write(m.tips.Bytes())
-			p.storage.WriteString(" ")
-			p.storage.Write(m.text.Bytes())
-			p.lock.Unlock()
+	if p.hooked {
+		p.fire(m)
+	}
+
+	p.release(m)
+}
+
+// 打印输出
+func (p *groPusher) print(c *groConsole, b []byte) {
+	if _, err := c.out.Write(b); err != nil {
+		if p.outFailed.CompareAndSwap(false, true) {
+			p.error(fmt.Errorf("print log: %w", err))
+		}
+		return
+	}
+	p.outFailed.Store(false)
+}
+
+// 渲染日志行 (控制台输出包含颜色和调用信息)
+func (p *groPusher) render(buf *bytes.Buffer, m *groMsg, console bool) {
+	color := ""
+	if console {
+		if colors := p.console(m.level).colors; colors != nil {
+			color = colors[m.level]
+		}
+	}
+	switch p.config.Style {
+	case StyleBrief:
+		p.writeTips(buf, m, color, console)
+		buf.WriteString(" ")
+	case StyleDetail:
+		p.writeTips(buf, m, color, console)
+		if console || p.config.SaveCaller {
+			buf.WriteString(" ")
+			buf.Write(m.stack.Bytes())
 		}
+		buf.WriteString(" ")
+	}
+	if console {
+		m.writeBody(buf, p.config.PrintEscape, p.config.Style == StyleDetail)
+	} else {
+		m.writeBody(buf, p.config.SaveEscape, p.config.Style == StyleDetail)
+	}
+}
+
+// 获取日志级别对应的打印输出
+func (p *groPusher) console(level int) *groConsole {
+	if level >= LevelWarning {
+		return p.errOut
 	}
+	return p.out
+}
 
-	if p.config.MsgCallback != nil {
-		p.config.GoExec(func() {
-			p.config.MsgCallback(m.level, m.text.String())
-		})
+// 写入日志提示 (颜色为空时不着色, 日志文件使用不同的时间格式时重新生成)
+func (p *groPusher) writeTips(buf *bytes.Buffer, m *groMsg, color string, console bool) {
+	buf.WriteString(color)
+	if console || p.config.saveTime == p.config.printTime {
+		buf.Write(m.tips.Bytes())
+	} else {
+		m.writeTips(buf, p.config.saveTime)
+	}
+	if color != "" {
+		buf.WriteString(levelStyleEnd)
 	}
+}
 
+// 触发日志钩子
+func (p *groPusher) fire(m *groMsg) {
+	var r Record
+	recorded := false
+	for i := range p.config.hooks {
+		h := &p.config.hooks[i]
+		if !h.enabled(m.level) {
+			continue
+		}
+		if !recorded {
+			r = m.record()
+			recorded = true
+		}
+		switch {
+		case h.asyn:
+			p.config.exec.Go(TaskCallback, func() {
+				if err := h.fire(r); err != nil {
+					p.config.error(err)
+				}
+			})
+		case p.hooks != nil: // 异步模式下同步钩子不在消费者协程中执行
+			p.hooks.Go(func() {
+				if err := h.fire(r); err != nil {
+					p.config.error(err)
+				}
+			})
+		default:
+			if err := h.fire(r); err != nil {
+				p.error(err)
+			}
+		}
+	}
+}
+
+// 报告推送时的内部错误 (异步模式下在消费者协程中推送, 通过执行器调用错误处理函数, 避免其记录日志时等待消费者自身)
+func (p *groPusher) error(err error) {
+	if !p.config.EnableAsyn {
+		p.config.error(err)
+		return
+	}
+	p.config.errs.Set(err)
+	p.config.exec.Go(TaskCallback, func() {
+		p.config.handle(err)
+	})
+}
+
+// 回收消息缓冲区
+func (p *groPusher) release(m *groMsg) {
+	m.name = ""
+	m.fields = nil
+	m.err = nil
+	if m.buf != nil {
+		putFieldBuf(m.buf)
+		m.buf = nil
+	}
+	m.causes = m.causes[:0]
 	switch p.config.Style {
 	case StyleBasic:
 		m.text.Reset()
@@ -180,8 +304,4 @@ func (p *groPusher) push(m *groMsg) {
 		p.bufferPool.Put(m.text)
 		p.bufferPool.Put(m.stack)
 	}
-
-	if m.level == LevelFatal {
-		go p.config.FatalHandling(p.config.logger, recover())
-	}
 }
@@ -0,0 +1,203 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	RedactAPIKey int = 1 << iota // API 密钥 (api_key=xxx、password=xxx、sk-xxx、AKIAxxx 等)
+	RedactBearer                 // Bearer 令牌
+	RedactEmail                  // 电子邮件地址
+	RedactCard                   // 信用卡号 (通过 Luhn 校验)
+
+	RedactAll = RedactAPIKey | RedactBearer | RedactEmail | RedactCard // 全部内置规则
+)
+
+// 内置脱敏规则 (包含两个分组的规则只替换第二个分组, 保留第一个分组)
+var redactBuiltins = []struct {
+	rule    int
+	pattern string
+}{
+	{RedactAPIKey, `(?i)(\b(?:api[_-]?key|access[_-]?key|secret[_-]?key|access[_-]?token|auth[_-]?token|client[_-]?secret|secret|password|passwd|pwd|token)\b["']?\s*[:=]\s*["']?)([^\s"'&,;]+)`},
+	{RedactAPIKey, `\b(?:sk|pk|rk)_(?:live|test)_[A-Za-z0-9]{10,}\b|\bsk-[A-Za-z0-9_-]{20,}|\bAKIA[0-9A-Z]{16}\b|\bgh[pousr]_[A-Za-z0-9]{36,}\b`},
+	{RedactBearer, `(?i)(\bbearer\s+)([A-Za-z0-9\-._~+/]+=*)`},
+	{RedactEmail, `\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`},
+}
+
+// 信用卡号候选 (13-19位数字, 允许空格或连字符分隔)
+var redactCardPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+
+// 脱敏规则
+type groRedactRule struct {
+	re   *regexp.Regexp // 匹配规则
+	keep bool           // 是否保留第一个分组
+}
+
+// 脱敏器
+type groRedactor struct {
+	rules  []groRedactRule     // 匹配规则
+	card   bool                // 是否脱敏信用卡号
+	fields map[string]struct{} // 脱敏字段名称 (小写)
+	mask   string              // 替换文本
+}
+
+// 创建脱敏器 (未配置任何规则时返回nil, 无效的正则表达式通过错误处理报告并忽略)
+func newRedactor(c *Config) *groRedactor {
+	if c.Redact == 0 && len(c.RedactPatterns) == 0 && len(c.RedactFields) == 0 {
+		return nil
+	}
+
+	r := &groRedactor{
+		card:   c.Redact&RedactCard != 0,
+		fields: make(map[string]struct{}, len(c.RedactFields)),
+		mask:   c.RedactMask,
+	}
+	for _, builtin := range redactBuiltins {
+		if c.Redact&builtin.rule != 0 {
+			re := regexp.MustCompile(builtin.pattern)
+			r.rules = append(r.rules, groRedactRule{re: re, keep: re.NumSubexp() == 2})
+		}
+	}
+	for _, pattern := range c.RedactPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			c.error(fmt.Errorf("redact pattern %q: %w", pattern, err))
+			continue
+		}
+		r.rules = append(r.rules, groRedactRule{re: re})
+	}
+	for _, name := range c.RedactFields {
+		r.fields[strings.ToLower(name)] = struct{}{}
+	}
+	return r
+}
+
+// 脱敏日志消息 (消息正文、错误链和附加字段)
+func (r *groRedactor) apply(m *groMsg) {
+	if text, ok := r.redact(m.text.Bytes()); ok {
+		m.text.Reset()
+		m.text.Write(text)
+	}
+
+	for i, cause := range m.causes {
+		if b, ok := r.redact([]byte(cause)); ok {
+			m.causes[i] = string(b)
+		}
+	}
+
+	// 钩子获取的错误替换为脱敏后的错误信息 (不保留原始错误, 避免通过错误链获取原文)
+	if m.err != nil {
+		if b, ok := r.redact([]byte(m.err.Error())); ok {
+			m.err = &groRedactedError{text: string(b)}
+		}
+	}
+
+	// 附加字段与日志器共享, 修改前复制
+	copied := false
+	for i, f := range m.fields {
+		value, ok := r.redactField(f)
+		if !ok {
+			continue
+		}
+		if !copied {
+			m.fields = append([]Field(nil), m.fields...)
+			copied = true
+		}
+		m.fields[i] = Any(f.Key, value)
+	}
+}
+
+// 脱敏后的错误
+type groRedactedError struct {
+	text string // 脱敏后的错误信息
+}
+
+func (e *groRedactedError) Error() string {
+	return e.text
+}
+
+// 脱敏字段值 (字段名称匹配时整体替换, 否则按规则脱敏字符串值)
+func (r *groRedactor) redactField(f Field) (any, bool) {
+	if _, ok := r.fields[strings.ToLower(f.Key)]; ok {
+		return r.mask, true
+	}
+
+	s, ok := f.string()
+	if !ok {
+		return nil, false
+	}
+	if b, ok := r.redact([]byte(s)); ok {
+		return string(b), true
+	}
+	return nil, false
+}
+
+// 脱敏消息文本 (未配置脱敏规则时原样返回)
+func (r *groRedactor) text(s string) string {
+	if r == nil {
+		return s
+	}
+	if b, ok := r.redact([]byte(s)); ok {
+		return string(b)
+	}
+	return s
+}
+
+// 脱敏文本 (未匹配任何规则时返回false)
+func (r *groRedactor) redact(b []byte) ([]byte, bool) {
+	changed := false
+	for _, rule := range r.rules {
+		if !rule.re.Match(b) {
+			continue
+		}
+		if rule.keep {
+			b = rule.re.ReplaceAll(b, []byte("${1}"+strings.ReplaceAll(r.mask, "$", "$$")))
+		} else {
+			b = rule.re.ReplaceAllLiteral(b, []byte(r.mask))
+		}
+		changed = true
+	}
+	if r.card && redactCardPattern.Match(b) {
+		b = redactCardPattern.ReplaceAllFunc(b, func(s []byte) []byte {
+			if !luhnValid(s) {
+				return s
+			}
+			changed = true
+			return []byte(r.mask)
+		})
+	}
+	return b, changed
+}
+
+// Luhn 校验 (忽略空格和连字符)
+func luhnValid(s []byte) bool {
+	digits := bytes.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, s)
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
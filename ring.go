@@ -0,0 +1,86 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"sync/atomic"
+)
+
+// 消息环形队列 (有界, 多生产者单消费者, 无锁)
+//
+// 每个槽位带有序号, 生产者通过 CAS 抢占写入位置, 写入完成后发布序号;
+// 消费者根据序号判断槽位是否可读, 读取后将序号推进一轮以释放槽位.
+type groRing struct {
+	mask  uint64        // 容量掩码
+	slots []groRingSlot // 槽位
+	_     [56]byte      // 填充, 避免伪共享
+	tail  atomic.Uint64 // 写入位置 (生产者共享)
+	_     [56]byte      // 填充, 避免伪共享
+	head  uint64        // 读取位置 (仅消费者访问)
+}
+
+// 环形队列槽位
+type groRingSlot struct {
+	seq atomic.Uint64 // 槽位序号
+	msg *groMsg       // 消息对象
+}
+
+// 创建环形队列 (容量向上取整为2的幂, 最小为2: 容量为1时槽位已写入和已释放的序号相同, 无法区分)
+func newRing(size int) *groRing {
+	capacity := uint64(2)
+	for capacity < uint64(size) {
+		capacity <<= 1
+	}
+
+	r := &groRing{
+		mask:  capacity - 1,
+		slots: make([]groRingSlot, capacity),
+	}
+	for i := range r.slots {
+		r.slots[i].seq.Store(uint64(i))
+	}
+	return r
+}
+
+// 写入消息 (队列已满时返回false)
+func (r *groRing) Push(m *groMsg) bool {
+	pos := r.tail.Load()
+	for {
+		slot := &r.slots[pos&r.mask]
+		seq := slot.seq.Load()
+		switch diff := int64(seq - pos); {
+		case diff == 0:
+			if r.tail.CompareAndSwap(pos, pos+1) {
+				slot.msg = m
+				slot.seq.Store(pos + 1)
+				return true
+			}
+			pos = r.tail.Load()
+		case diff < 0:
+			return false
+		default:
+			pos = r.tail.Load()
+		}
+	}
+}
+
+// 读取消息 (队列为空时返回false, 仅允许单个消费者调用)
+func (r *groRing) Pop() (*groMsg, bool) {
+	slot := &r.slots[r.head&r.mask]
+	if slot.seq.Load() != r.head+1 {
+		return nil, false
+	}
+	m := slot.msg
+	slot.msg = nil
+	slot.seq.Store(r.head + r.mask + 1)
+	r.head++
+	return m, true
+}
+
+// 是否有可读消息 (仅允许消费者调用)
+func (r *groRing) Ready() bool {
+	return r.slots[r.head&r.mask].seq.Load() == r.head+1
+}
@@ -0,0 +1,60 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"context"
+	"encoding/hex"
+	"unsafe"
+)
+
+// 调用链字段名称
+const (
+	SpanTraceIDKey    = "trace_id"    // 调用链ID (32位十六进制)
+	SpanIDKey         = "span_id"     // 跨度ID (16位十六进制)
+	SpanTraceFlagsKey = "trace_flags" // 调用链标志 (2位十六进制, 01表示已采样)
+)
+
+// 调用链信息
+type SpanContext struct {
+	TraceID    [16]byte // 调用链ID
+	SpanID     [8]byte  // 跨度ID
+	TraceFlags byte     // 调用链标志
+}
+
+// 调用链提取器 (从上下文中获取当前跨度, 如 OpenTelemetry 的 trace.SpanContextFromContext)
+type SpanExtractor interface {
+	Span(ctx context.Context) (SpanContext, bool) // 获取调用链信息 (上下文中没有有效跨度时返回false)
+}
+
+// 函数调用链提取器
+type SpanExtractorFunc func(ctx context.Context) (SpanContext, bool)
+
+// 获取调用链信息
+func (f SpanExtractorFunc) Span(ctx context.Context) (SpanContext, bool) {
+	return f(ctx)
+}
+
+// 添加调用链字段 (十六进制文本写入缓冲区, 不分配内存)
+func (fb *groFieldBuf) appendSpan(ctx context.Context, e SpanExtractor) {
+	if ctx == nil || e == nil {
+		return
+	}
+	span, ok := e.Span(ctx)
+	if !ok {
+		return
+	}
+	b := fb.span[:]
+	flags := [1]byte{span.TraceFlags}
+	hex.Encode(b[0:32], span.TraceID[:])
+	hex.Encode(b[32:48], span.SpanID[:])
+	hex.Encode(b[48:50], flags[:])
+	fb.fields = append(fb.fields,
+		Str(SpanTraceIDKey, unsafe.String(&b[0], 32)),
+		Str(SpanIDKey, unsafe.String(&b[32], 16)),
+		Str(SpanTraceFlagsKey, unsafe.String(&b[48], 2)),
+	)
+}
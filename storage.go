@@ -7,13 +7,15 @@ package grolog
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 )
@@ -21,35 +23,48 @@ import (
 // 日志存储器
 type groStorage struct {
 	config       *Config
+	flusher      *groFlusher
+	fallback     *groFallback
+	chain        *groChain // 链式校验器 (未启用链式校验时为空)
 	file         *os.File
 	out          *bufio.Writer
+	count        *groCountWriter // 日志文件写入计数 (写入失败时确定日志消息已写入的部分)
 	lock         sync.Mutex
 	err          error
+	dirty        bool
+	cleaning     atomic.Bool
+	retryDelay   time.Duration // 重新打开间隔 (每次失败后翻倍)
+	retryTime    time.Time     // 下次重新打开时间
 	currFileNum  int
 	currFileSize int64
+	currFileMsgs bool    // 当前日志文件是否已有日志消息
+	pending      []error // 待报告的错误 (持有锁时记录, 释放锁后报告)
 }
 
 // 创建新的存储器
-func newStorage(config *Config) *groStorage {
+func newStorage(config *Config, flusher *groFlusher) *groStorage {
 	s := &groStorage{
-		config: config,
+		config:   config,
+		flusher:  flusher,
+		fallback: newFallback(config.Fallback, config.memory),
 	}
-
-	if duration, err := time.ParseDuration(s.config.ExpireTime); err == nil && duration > 0 {
-		cleanExpireFiles(s.config.FileDir, s.config.FileName, duration)
+	if config.EnableChain {
+		s.chain = newChain(config.ChainKey)
 	}
 
-	err := s.open(false)
-	if err != nil {
-		s.err = err
+	s.lock.Lock()
+	if err := s.open(false); err != nil {
+		s.fail(err)
 	}
+	s.goCleanExpire()
+	s.unlock()
 	return s
 }
 
 // 停止存储器
 func (s *groStorage) Close() {
 	s.lock.Lock()
-	defer s.lock.Unlock()
+	defer s.unlock()
 
 	if s.out == nil {
 		return
@@ -61,14 +76,21 @@ func (s *groStorage) Close() {
 // 刷新缓冲区
 func (s *groStorage) Flush() {
 	s.lock.Lock()
-	defer s.lock.Unlock()
+	defer s.unlock()
 
 	if s.out == nil {
 		return
 	}
 
-	s.out.Flush()
-	s.file.Sync()
+	if err := s.out.Flush(); err != nil {
+		s.fail(fmt.Errorf("flush log file: %w", err))
+		return
+	}
+	if err := s.file.Sync(); err != nil {
+		s.fail(fmt.Errorf("sync log file: %w", err))
+		return
+	}
+	s.clean()
 }
 
 // 错误
@@ -90,33 +112,136 @@ func (s *groStorage) IsValid() bool {
 // 写入日志消息
 func (s *groStorage) Write(b []byte) {
 	s.lock.Lock()
-	defer s.lock.Unlock()
+	defer s.unlock()
 
-	if s.out == nil {
+	if s.out == nil && !s.retry() {
+		s.fallback.Write(b)
 		return
 	}
 
-	{
-		size := int64(len(b))
-		for size > 0 {
-			available := s.config.MaxFileSize - s.currFileSize
-			if size < available {
-				s.out.Write(b)
-				s.currFileSize += size
-				break
-			}
-			s.out.Write(b[:available])
-			s.currFileSize += size
-			size -= available
-			b = b[available:]
+	line := b
+	if s.chain != nil {
+		b = s.chain.link(b)
+	}
+	// 写入失败时只将未写入日志文件的部分写入备用输出 (未写入任何部分时写入原始日志消息)
+	full := len(b)
+	fallback := func(rest []byte) {
+		if len(rest) == full {
+			rest = line
+		}
+		s.fallback.Write(rest)
+	}
+
+	var part []byte // 写入当前日志文件的部分
+	var start int64 // 写入当前日志文件的位置
+	for len(b) > 0 {
+		available := s.config.MaxFileSize - s.currFileSize
+		// 启用链式校验时日志消息不跨文件拆分 (当前日志文件为空时除外)
+		if available <= 0 || (s.chain != nil && available < int64(len(b)) && s.currFileMsgs) {
 			if err := s.nextFile(); err != nil {
+				fallback(b)
 				return
 			}
+			continue
+		}
+		n := int64(len(b))
+		if n > available {
+			n = available
+		}
+		part, start = b[:n], s.currFileSize
+		if _, err := s.out.Write(part); err != nil {
+			count := s.count
+			s.fail(fmt.Errorf("write log file: %w", err))
+			fallback(b[count.written(start, n):])
+			return
 		}
+		s.currFileSize += n
+		s.currFileMsgs = true
+		b = b[n:]
+	}
+	if s.chain != nil {
+		s.chain.commit()
 	}
 
 	if s.config.MaxWriteBuffer == 0 {
-		s.out.Flush()
+		if err := s.out.Flush(); err != nil {
+			count := s.count
+			s.fail(fmt.Errorf("write log file: %w", err))
+			fallback(part[count.written(start, int64(len(part))):])
+			return
+		}
+	}
+	if s.out.Buffered() == 0 {
+		s.clean()
+	} else if !s.dirty {
+		s.dirty = true
+		s.flusher.Dirty()
+	}
+}
+
+// 日志文件不可用 (报告错误并关闭日志文件, 之后的日志消息写入备用输出, 直到重新打开成功)
+func (s *groStorage) fail(err error) {
+	s.report(err)
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.out = nil
+	s.file = nil
+	s.clean()
+
+	s.retryDelay, _ = time.ParseDuration(s.config.RetryInterval)
+	s.retryTime = time.Now().Add(s.retryDelay)
+}
+
+// 记录错误 (需持有锁, 错误处理函数可能记录日志并再次写入, 释放锁后才调用)
+func (s *groStorage) report(err error) {
+	s.err = err
+	s.config.errs.Set(err)
+	s.pending = append(s.pending, err)
+}
+
+// 释放锁并报告持有锁期间记录的错误 (异步模式下通过执行器报告, 避免消费者协程写入已满的消息队列)
+func (s *groStorage) unlock() {
+	if len(s.pending) == 0 {
+		s.lock.Unlock()
+		return
+	}
+	errs := s.pending
+	s.pending = nil
+	s.lock.Unlock()
+
+	if s.config.EnableAsyn {
+		s.config.exec.Go(TaskCallback, func() {
+			for _, err := range errs {
+				s.config.handle(err)
+			}
+		})
+		return
+	}
+	for _, err := range errs {
+		s.config.handle(err)
+	}
+}
+
+// 重新打开日志文件 (未到重新打开时间或打开失败时返回false)
+func (s *groStorage) retry() bool {
+	if s.retryDelay <= 0 || time.Now().Before(s.retryTime) {
+		return false
+	}
+	if err := s.open(false); err != nil {
+		s.err = err
+		s.retryDelay = min(s.retryDelay*2, maxRetryInterval)
+		s.retryTime = time.Now().Add(s.retryDelay)
+		return false
+	}
+	return true
+}
+
+// 标记缓冲区已刷新
+func (s *groStorage) clean() {
+	if s.dirty {
+		s.dirty = false
+		s.flusher.Reset()
 	}
 }
 
@@ -128,27 +253,16 @@ func (s *groStorage) WriteString(text string) {
 
 // 递归创建目录
 func createNestedDirs(path string) error {
-	dirs := strings.Split(path, string(filepath.Separator))
-	for i := range dirs {
-		subDir := strings.Join(dirs[:i+1], string(filepath.Separator))
-		if _, err := os.Stat(subDir); err != nil {
-			if !os.IsNotExist(err) {
-				return err
-			}
-			if err = os.MkdirAll(subDir, 0755); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+	return os.MkdirAll(path, 0755)
 }
 
-// 清理过期文件
-func cleanExpireFiles(dir string, name string, expireTime time.Duration) {
+// 清理过期文件 (删除修改时间早于过期时间的日志文件, 不删除当前日志文件)
+func cleanExpireFiles(dir string, name string, current string, expireTime time.Duration) {
 	if expireTime <= 0 {
 		return
 	}
 	expired := time.Now().Add(-expireTime)
+	re := logFileRe(name)
 	filepath.Walk(dir, func(path string, info os.FileInfo, err error) (r error) {
 		if err != nil {
 			return
@@ -157,22 +271,39 @@ func cleanExpireFiles(dir string, name string, expireTime time.Duration) {
 			return
 		}
 
-		// 使用正则表达式验证
-		re := regexp.MustCompile(fmt.Sprintf(`^%s.+\.log$`, name))
-		matches := re.FindStringSubmatch(info.Name())
-
 		// 检查是否满足预期的日志文件名
-		if matches == nil {
+		if !re.MatchString(info.Name()) || path == current {
 			return
 		}
 
-		if expired.Before(info.ModTime()) {
+		if info.ModTime().Before(expired) {
 			os.Remove(path)
 		}
 		return
 	})
 }
 
+// 日志文件名匹配 (Prefix[_StartTime_PID][(CurrFileNum)].log, 不匹配以相同前缀开头的其他日志器的文件)
+func logFileRe(name string) *regexp.Regexp {
+	return regexp.MustCompile(`^` + regexp.QuoteMeta(name) + `(_\d{12}_\d+)?(\(\d+\))?\.log$`)
+}
+
+// 后台清理过期文件 (打开日志文件后调用, 清理未结束时跳过)
+func (s *groStorage) goCleanExpire() {
+	duration, err := time.ParseDuration(s.config.ExpireTime)
+	if err != nil || duration <= 0 {
+		return
+	}
+	if !s.cleaning.CompareAndSwap(false, true) {
+		return
+	}
+	current := s.filePath()
+	s.config.exec.Go(TaskCleaner, func() {
+		defer s.cleaning.Store(false)
+		cleanExpireFiles(s.config.FileDir, s.config.FileName, current, duration)
+	})
+}
+
 // 获取文件大小
 func (s *groStorage) getFileSize(file *os.File) (int64, error) {
 	stat, err := file.Stat()
@@ -182,71 +313,166 @@ func (s *groStorage) getFileSize(file *os.File) (int64, error) {
 	return stat.Size(), nil
 }
 
+// 获取当前日志文件路径 (Prefix_StartTime_PID(CurrFileNum).log)
+func (s *groStorage) filePath() string {
+	num := ""
+	if s.currFileNum > 0 {
+		num = fmt.Sprintf("(%d)", s.currFileNum)
+	}
+	if s.config.EnableFileTime {
+		name := s.config.FileName + "_" + s.config.startTime.Format("060102150405") + "_" + strconv.Itoa(os.Getpid()) + num + ".log"
+		return filepath.Join(s.config.FileDir, name)
+	}
+	return filepath.Join(s.config.FileDir, s.config.FileName+num+".log")
+}
+
 // 打开日志文件
 func (s *groStorage) open(clear bool) (err error) {
 	var flag int
 	if clear {
-		flag = os.O_CREATE | os.O_WRONLY
+		flag = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
 	} else {
-		flag = os.O_APPEND
+		flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
 	}
 
-	// 生成日志文件名 (Prefix_StartTime_PID(CurrFileNum).log)
-	name := ""
-	if s.config.EnableFileTime {
-		num := ""
-		if s.currFileNum > 0 {
-			num = fmt.Sprintf("(%d)", s.currFileNum)
-		}
-		name = s.config.FileName + "_" + s.config.startTime.Format("060102150405") + "_" + strconv.Itoa(os.Getpid()) + num + ".log"
-		name = filepath.Join(s.config.FileDir, name)
-	} else {
-		num := ""
-		if s.currFileNum > 0 {
-			num = fmt.Sprintf("(%d)", s.currFileNum)
-		}
-		name = filepath.Join(s.config.FileDir, s.config.FileName+num+".log")
-	}
+	name := s.filePath()
 
 	// 目录不存在则创建
 	if _, err := os.Stat(s.config.FileDir); os.IsNotExist(err) {
-		createNestedDirs(s.config.FileDir)
-	}
-	// 文件不存在则创建
-	if _, err := os.Stat(name); os.IsNotExist(err) {
-		f, err := os.Create(name)
-		if err != nil {
-			fmt.Printf("create log file error, %v \n", err)
-			return err
+		if err := createNestedDirs(s.config.FileDir); err != nil {
+			return fmt.Errorf("create log dir: %w", err)
 		}
-		f.Close()
-	} else {
+	}
+	// 文件已存在则恢复权限
+	if _, err := os.Stat(name); err == nil {
 		os.Chmod(name, 0644)
 	}
 
 	file, err := os.OpenFile(name, flag, 0644)
-	if file == nil {
-		fmt.Printf("open log file error, %v \n", err)
-		return err
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
 	}
 	size, err := s.getFileSize(file)
 	if err != nil {
-		fmt.Printf("get log file size error, %v \n", err)
-		return err
+		file.Close()
+		return fmt.Errorf("get log file size: %w", err)
+	}
+
+	// 读取已存在的日志文件末尾 (加密的日志文件截断最后不完整的数据块)
+	var tail []byte
+	var state groEncryptState
+	if size > 0 && (s.chain != nil || s.config.aead != nil) {
+		var end int64
+		tail, end, state, err = s.readTail(name, size, s.config.aead != nil)
+		if errors.Is(err, errNotEncrypted) && !clear {
+			// 启用加密前写入的日志文件, 保留该文件并切换下一个日志文件
+			file.Close()
+			s.nextFileNum()
+			return s.open(true)
+		}
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("read log file: %w", err)
+		}
+		if end < size {
+			if err := file.Truncate(end); err != nil {
+				file.Close()
+				return fmt.Errorf("truncate log file: %w", err)
+			}
+			size = end
+		}
+	}
+
+	var out io.Writer = file
+	if s.config.aead != nil {
+		if size == 0 {
+			if state, err = writeEncryptHeader(file); err != nil {
+				file.Close()
+				return fmt.Errorf("write log file: %w", err)
+			}
+			size = int64(encryptHeadSize)
+		}
+		out = newEncrypter(s.config.aead, file, state)
 	}
 
 	s.file = file
-	s.out = bufio.NewWriterSize(s.file, s.config.MaxWriteBuffer)
+	s.count = &groCountWriter{w: out, n: size}
+	s.out = bufio.NewWriterSize(s.count, s.config.MaxWriteBuffer)
 	s.currFileSize = size
+	s.currFileMsgs = len(tail) > 0
+
+	// 新的日志文件或无法恢复校验值时写入链头 (新的进程先从最新的日志文件末尾恢复)
+	if s.chain != nil && !s.chain.resume(tail) {
+		if !s.chain.linked {
+			s.resumeLatest(name)
+		}
+		header := s.chain.header()
+		if len(tail) > 0 && tail[len(tail)-1] != '\n' { // 最后一行不完整 (进程崩溃), 链头从新行开始
+			header = append([]byte{'\n'}, header...)
+		}
+		if _, err := s.out.Write(header); err != nil {
+			s.file.Close()
+			s.out = nil
+			s.file = nil
+			return fmt.Errorf("write log file: %w", err)
+		}
+		s.currFileSize += int64(len(header))
+	}
 	return nil
 }
 
+// 从目录中最新的其他日志文件末尾恢复校验值 (没有可恢复的日志文件时保持全零的起始校验值)
+func (s *groStorage) resumeLatest(current string) {
+	files, err := ListLogFiles(s.config.FileDir, s.config.FileName)
+	if err != nil {
+		return
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		if filepath.Clean(files[i]) == filepath.Clean(current) {
+			continue
+		}
+		info, err := os.Stat(files[i])
+		if err != nil || info.Size() == 0 {
+			continue
+		}
+		tail, _, _, err := s.readTail(files[i], info.Size(), s.config.aead != nil)
+		if errors.Is(err, errNotEncrypted) { // 启用加密前写入的日志文件
+			tail, _, _, err = s.readTail(files[i], info.Size(), false)
+		}
+		if err == nil {
+			s.chain.resume(tail)
+		}
+		return
+	}
+}
+
+// 读取已存在的日志文件末尾的明文 (返回末尾明文、有效大小和加密文件状态, 加密的日志文件的有效大小不含最后不完整的数据块)
+func (s *groStorage) readTail(name string, size int64, encrypted bool) ([]byte, int64, groEncryptState, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, 0, groEncryptState{}, err
+	}
+	defer file.Close()
+
+	if encrypted {
+		return readEncryptedTail(file, size, s.config.aead, chainTailSize)
+	}
+	tail := make([]byte, min(size, int64(chainTailSize)))
+	if _, err := file.ReadAt(tail, size-int64(len(tail))); err != nil {
+		return nil, 0, groEncryptState{}, err
+	}
+	return tail, size, groEncryptState{}, nil
+}
+
 // 关闭日志文件
 func (s *groStorage) closeFile() {
-	s.out.Flush()
+	if err := s.out.Flush(); err != nil {
+		s.report(fmt.Errorf("flush log file: %w", err))
+	}
 	s.file.Close()
 	s.out = nil
 	s.file = nil
+	s.clean()
 }
 
 // 切换下一个文件序号
@@ -261,13 +487,29 @@ func (s *groStorage) nextFileNum() {
 func (s *groStorage) nextFile() error {
 	s.closeFile()
 	s.nextFileNum()
-	if duration, err := time.ParseDuration(s.config.ExpireTime); err == nil && duration > 0 {
-		cleanExpireFiles(s.config.FileDir, s.config.FileName, duration)
-	}
 	err := s.open(true)
+	s.goCleanExpire()
 	if err != nil {
-		s.err = err
+		s.fail(err)
 		return err
 	}
 	return nil
 }
+
+// 计数写入器 (统计已写入日志文件的数据大小, 加密的日志文件统计已写入的明文大小)
+type groCountWriter struct {
+	w io.Writer
+	n int64 // 已写入的大小 (包含打开时日志文件的大小)
+}
+
+// 写入数据
+func (c *groCountWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// 获取从 start 开始的 size 字节中已写入的大小
+func (c *groCountWriter) written(start int64, size int64) int64 {
+	return min(max(c.n-start, 0), size)
+}
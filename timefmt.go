@@ -0,0 +1,76 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	TimeUnix      = "unix"   // Unix 时间戳 (秒)
+	TimeUnixMilli = "unixms" // Unix 时间戳 (毫秒)
+	TimeUnixMicro = "unixus" // Unix 时间戳 (微秒)
+	TimeUnixNano  = "unixns" // Unix 时间戳 (纳秒)
+)
+
+// 时间格式
+type groTimeFormat struct {
+	layout string         // 时间布局 (Unix 时间戳时为空)
+	unix   string         // Unix 时间戳精度 (时间布局时为空)
+	loc    *time.Location // 时区
+}
+
+// 创建时间格式
+func newTimeFormat(layout string, loc *time.Location) *groTimeFormat {
+	switch layout {
+	case TimeUnix, TimeUnixMilli, TimeUnixMicro, TimeUnixNano:
+		return &groTimeFormat{unix: layout, loc: loc}
+	}
+	return &groTimeFormat{layout: layout, loc: loc}
+}
+
+// 写入格式化时间
+func (f *groTimeFormat) write(buf *bytes.Buffer, t time.Time) {
+	var b []byte
+	switch f.unix {
+	case "":
+		b = t.In(f.loc).AppendFormat(buf.AvailableBuffer(), f.layout)
+	case TimeUnix:
+		b = strconv.AppendInt(buf.AvailableBuffer(), t.Unix(), 10)
+	case TimeUnixMilli:
+		b = strconv.AppendInt(buf.AvailableBuffer(), t.UnixMilli(), 10)
+	case TimeUnixMicro:
+		b = strconv.AppendInt(buf.AvailableBuffer(), t.UnixMicro(), 10)
+	case TimeUnixNano:
+		b = strconv.AppendInt(buf.AvailableBuffer(), t.UnixNano(), 10)
+	}
+	buf.Write(b)
+}
+
+// 解析时区 (为空或 Local 时为本地时区, 支持 UTC、IANA 时区名称和固定偏移如 +08:00)
+func parseTimeZone(zone string) (*time.Location, error) {
+	switch zone {
+	case "", "Local":
+		return time.Local, nil
+	case "UTC", "Z":
+		return time.UTC, nil
+	}
+	if zone[0] == '+' || zone[0] == '-' {
+		t, err := time.Parse("-07:00", zone)
+		if err != nil {
+			t, err = time.Parse("-0700", zone)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone offset %q", zone)
+		}
+		_, offset := t.Zone()
+		return time.FixedZone(zone, offset), nil
+	}
+	return time.LoadLocation(zone)
+}
@@ -0,0 +1,63 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+// 调用堆栈深度上限
+const maxTraceDepth = 32
+
+// 获取调用堆栈
+func (m *groMsg) initTrace(layer int) {
+	// 调用层级: runtime.Callers -> initTrace -> pusher.assign -> handler.Log -> Logger.X -> 调用处
+	if cap(m.trace) < maxTraceDepth {
+		m.trace = make([]uintptr, maxTraceDepth)
+	}
+	m.trace = m.trace[:runtime.Callers(2+callerDepth+layer, m.trace[:maxTraceDepth])]
+}
+
+// 遍历调用堆栈 (到 runtime.goexit 为止)
+func rangeTrace(pcs []uintptr, f func(frame runtime.Frame)) {
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.Function == "runtime.goexit" {
+			return
+		}
+		f(frame)
+		if !more {
+			return
+		}
+	}
+}
+
+// 写入调用堆栈 (作为消息正文的后续行, 每个调用帧两行: 函数名称, 缩进的文件和行号, 按消息正文的方式转义)
+func writeTrace(buf *bytes.Buffer, pcs []uintptr, escape int) {
+	newline := escapedNewline(escape)
+	rangeTrace(pcs, func(frame runtime.Frame) {
+		buf.WriteString(newline)
+		buf.WriteString("\t")
+		writeEscaped(buf, []byte(frame.Function), escape)
+		buf.WriteString(newline)
+		buf.WriteString("\t\t")
+		writeEscaped(buf, []byte(frame.File), escape)
+		buf.WriteString(":")
+		buf.WriteString(strconv.Itoa(frame.Line))
+	})
+}
+
+// 生成调用堆栈 (每个调用帧格式: 函数名称 文件:行号)
+func traceStrings(pcs []uintptr) []string {
+	var stack []string
+	rangeTrace(pcs, func(frame runtime.Frame) {
+		stack = append(stack, frame.Function+" "+frame.File+":"+strconv.Itoa(frame.Line))
+	})
+	return stack
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 The Gromb Authors. All rights reserved.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package grolog
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 调用位置级别规则
+type groVModuleRule struct {
+	pattern string // 匹配模式 (不含 .go 后缀)
+	depth   int    // 匹配的路径层数 (模式中 / 的数量加1)
+	level   int    // 日志级别
+}
+
+// 调用位置级别 (按调用位置所在文件覆盖日志级别, 每个调用位置只匹配一次)
+type groVModule struct {
+	rules []groVModuleRule                // 匹配规则 (按配置顺序, 首个匹配的规则生效)
+	mutex sync.Mutex                      // 缓存更新锁
+	cache atomic.Pointer[map[uintptr]int] // 调用位置到日志级别的缓存 (写时复制, 读取不加锁, 未匹配任何规则时为-1)
+}
+
+// 级别名称
+var vmoduleLevels = map[string]int{
+	"verbose": LevelVerBose,
+	"debug":   LevelDebug,
+	"trace":   LevelTrace,
+	"warning": LevelWarning,
+	"warn":    LevelWarning,
+	"error":   LevelError,
+	"fatal":   LevelFatal,
+	"panic":   LevelPanic,
+}
+
+// 创建调用位置级别 (未配置任何规则时返回nil, 无效的规则通过错误处理报告并忽略)
+func newVModule(c *Config) *groVModule {
+	v := &groVModule{}
+	v.cache.Store(&map[uintptr]int{})
+	for _, item := range strings.Split(c.VModule, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		rule, err := parseVModuleRule(item)
+		if err != nil {
+			c.error(fmt.Errorf("vmodule %q: %w", item, err))
+			continue
+		}
+		v.rules = append(v.rules, rule)
+	}
+	if len(v.rules) == 0 {
+		return nil
+	}
+	return v
+}
+
+// 解析调用位置级别规则 (格式: 模式=级别, 级别为名称或数值)
+func parseVModuleRule(item string) (groVModuleRule, error) {
+	pattern, name, ok := strings.Cut(item, "=")
+	pattern, name = strings.TrimSuffix(strings.TrimSpace(pattern), ".go"), strings.TrimSpace(name)
+	if !ok || pattern == "" {
+		return groVModuleRule{}, fmt.Errorf("want pattern=level")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return groVModuleRule{}, err
+	}
+
+	level, ok := vmoduleLevels[strings.ToLower(name)]
+	if !ok {
+		n, err := strconv.Atoi(name)
+		if err != nil || n < LevelVerBose || n > LevelPanic {
+			return groVModuleRule{}, fmt.Errorf("invalid level %q", name)
+		}
+		level = n
+	}
+	return groVModuleRule{pattern: pattern, depth: strings.Count(pattern, "/") + 1, level: level}, nil
+}
+
+// 获取调用位置的日志级别 (未匹配任何规则时返回默认级别)
+func (v *groVModule) level(pc uintptr, def int) int {
+	level, ok := (*v.cache.Load())[pc]
+	if !ok {
+		level = v.store(pc, v.match(pc))
+	}
+	if level < 0 {
+		return def
+	}
+	return level
+}
+
+// 缓存调用位置的日志级别 (复制缓存后添加, 调用位置数量有限, 只在首次调用时复制)
+func (v *groVModule) store(pc uintptr, level int) int {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	cache := *v.cache.Load()
+	if cached, ok := cache[pc]; ok {
+		return cached
+	}
+	next := make(map[uintptr]int, len(cache)+1)
+	for k, l := range cache {
+		next[k] = l
+	}
+	next[pc] = level
+	v.cache.Store(&next)
+	return level
+}
+
+// 匹配调用位置所在文件 (模式不含 / 时匹配文件名, 否则匹配路径末尾相同层数的部分, 均不含 .go 后缀)
+func (v *groVModule) match(pc uintptr) int {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	file := strings.TrimSuffix(frame.File, ".go")
+	for _, rule := range v.rules {
+		name := file
+		for i, n := len(file)-1, 0; i >= 0; i-- {
+			if file[i] == '/' {
+				if n++; n == rule.depth {
+					name = file[i+1:]
+					break
+				}
+			}
+		}
+		if ok, _ := path.Match(rule.pattern, name); ok {
+			return rule.level
+		}
+	}
+	return -1
+}